@@ -11,59 +11,65 @@ import (
 	"time"
 
 	"github.com/codesoap/den/database"
+	"github.com/codesoap/den/internal/archive"
+	"github.com/codesoap/den/internal/doctext"
+	"github.com/codesoap/den/internal/executable"
+	"github.com/codesoap/den/internal/exif"
 	"github.com/codesoap/den/internal/mediainfo"
 	"github.com/codesoap/den/internal/mimecat"
+	"github.com/codesoap/den/internal/phash"
 
 	"github.com/djherbis/times"
 )
 
 type addition struct {
-	path      string
-	d         fs.DirEntry
-	mime      string
-	mediainfo mediainfo.Info
+	path       string
+	d          fs.DirEntry
+	mime       string
+	category   mimecat.Category
+	file       *database.File
+	mediainfo  mediainfo.Info
+	text       string
+	members    []database.Entry
+	executable executable.Info
+	location   *database.Location
+	phash      *uint64
 }
 
 type Progress struct{ Done, Total int }
 
-// Add adds the given path to the tracked paths and indexes all
-// non-hidden files in path. If a path is already tracked, an error will
-// be returned.
+// Add adds the given path to the tracked paths and indexes all files in
+// path, that are not skipped according to opts or the patterns of
+// .denignore files. Files that share their size with another file are
+// hashed, to allow finding duplicates. If a path is already tracked, an
+// error will be returned.
 //
+// The files are analyzed by the given number of concurrent jobs.
 // Progress updates will be written roughly once per second to the
 // progress channel. The progress channel will be closed before the
 // function returns.
-func Add(path string, db database.DB, progress chan Progress) error {
+func Add(path string, opts database.TrackOptions, db database.DB, jobs int, progress chan Progress) error {
 	defer close(progress)
 	path, err := filepath.Abs(path)
 	if err != nil {
 		return fmt.Errorf("could not normalize path: %s", err)
 	}
-	if err := db.TrackPath(path); err != nil {
+	t, err := newTree(path, opts)
+	if err != nil {
+		return err
+	}
+	if err := db.TrackPath(path, opts); err != nil {
 		return fmt.Errorf("could not track path '%s': %s", path, err)
 	}
 	prog := Progress{}
 	var lastProgressUpdate time.Time
 	paths := make(map[string]fs.DirEntry)
-	err = filepath.WalkDir(path,
-		func(path string, d fs.DirEntry, err error) error {
+	err = t.walk(path,
+		func(path string, d fs.DirEntry) error {
 			if time.Since(lastProgressUpdate) >= time.Second {
 				progress <- prog
 				lastProgressUpdate = time.Now()
 			}
-			if err != nil {
-				return err
-			}
-			hidden, err := isHiddenFile(d.Name())
-			if err != nil {
-				f := "could not determine if '%s' is hidden: %s"
-				return fmt.Errorf(f, path, err)
-			} else if hidden {
-				if d.IsDir() {
-					return fs.SkipDir
-				}
-				return nil
-			}
 			if !d.Type().IsRegular() {
 				return nil
 			}
@@ -76,87 +82,134 @@ func Add(path string, db database.DB, progress chan Progress) error {
 		return fmt.Errorf("could not index files: %s", err)
 	}
 
-	entriesInTx := 0
-	for path, d := range paths {
-		if time.Since(lastProgressUpdate) >= time.Second {
-			progress <- prog
-			lastProgressUpdate = time.Now()
-		}
-		if entriesInTx == 0 {
-			if err := db.BeginTx(); err != nil {
-				return fmt.Errorf("could not start transaction: %s", err)
-			}
-		}
-		entriesInTx++
-		if err = indexFile(db, path, d); err != nil {
-			_ = db.Rollback()
-			return err
-		}
-		if entriesInTx == 1_000 {
-			entriesInTx = 0
-			if err2 := db.Commit(); err2 != nil {
-				return fmt.Errorf("could not commit transaction: %s", err2)
-			}
-		}
-		prog.Done++
+	if err = indexFiles(db, paths, jobs, progress, &prog); err != nil {
+		return err
 	}
-	if entriesInTx > 0 {
-		if err := db.Commit(); err != nil {
-			return fmt.Errorf("could not commit transaction: %s", err)
-		}
+	if err = hashDuplicateCandidates(db); err != nil {
+		return err
 	}
 	prog.Done = prog.Total
 	progress <- prog
 	return nil
 }
 
+// indexFile analyzes the file at path and adds it to the database.
+// db.BeginTx must have been called before.
 func indexFile(db database.DB, path string, d fs.DirEntry) error {
-	_, err := d.Info()
+	a, err := analyzeFile(path, d)
 	if err != nil {
-		return fmt.Errorf("could not get file info on '%s': %s", path, err)
+		return err
 	}
+	return storeFile(db, a)
+}
+
+// analyzeFile gathers all information about the file at path, that is
+// needed to add it to the database. It does not access the database, so
+// it may be called concurrently.
+func analyzeFile(path string, d fs.DirEntry) (addition, error) {
 	m, err := determineMIME(path)
 	if err != nil {
-		return fmt.Errorf("could not determine mime type of '%s': %s", path, err)
+		return addition{}, fmt.Errorf("could not determine mime type of '%s': %s", path, err)
 	}
 	a := addition{
-		path: path,
-		d:    d,
-		mime: m,
+		path:     path,
+		d:        d,
+		mime:     m,
+		category: mimecat.MIMEToCategory(m),
+	}
+	if a.category == mimecat.Executable || a.category == mimecat.Document || a.category == mimecat.Other {
+		// Files with the exec bit are only considered executables, if they
+		// fit no other category; file systems like FAT mark all files as
+		// executable.
+		info, ok, err := executable.Inspect(path)
+		if err == nil && (ok || a.category == mimecat.Other && isExecutable(d)) {
+			a.category = mimecat.Executable
+			a.executable = info
+		} else if a.category == mimecat.Executable {
+			// Unreadable ELF binary.
+			a.category = mimecat.Other
+		}
 	}
-	cat := mimecat.MIMEToCategory(m)
-	if cat == mimecat.Video || cat == mimecat.Audio || cat == mimecat.Picture {
+	if a.category == mimecat.Video || a.category == mimecat.Audio || a.category == mimecat.Picture {
 		a.mediainfo, _ = mediainfo.MediaInfo(path)
 	}
-	switch {
-	case cat == mimecat.Other:
-		if err = addFile(a, db); err != nil {
-			return fmt.Errorf("could not add other file '%s': %s", path, err)
+	if a.category == mimecat.Video && a.mediainfo.Type == mediainfo.TypeAudio {
+		a.category = mimecat.Audio
+	}
+	if a.category == mimecat.Document {
+		// Documents without extractable text are indexed anyway:
+		a.text, _ = doctext.Extract(path, m)
+	}
+	if a.category == mimecat.Archive {
+		// Archives that cannot be read are indexed without members:
+		members, _ := archive.List(path, m)
+		a.members = toMembers(path, members)
+	}
+	if a.file, err = toFile(a); err != nil {
+		return a, err
+	}
+	switch a.category {
+	case mimecat.Picture:
+		// Pictures with unreadable metadata are indexed anyway:
+		m, _ := exif.Read(path)
+		a.file.Taken = m.Taken
+		if m.Rotated {
+			a.mediainfo.Width, a.mediainfo.Height = a.mediainfo.Height, a.mediainfo.Width
+		}
+		if h, err := phash.DHash(path); err == nil {
+			a.phash = &h
+		}
+		if l := m.Location; l != nil {
+			a.location = &database.Location{Latitude: l.Latitude, Longitude: l.Longitude, Altitude: l.Altitude}
 		}
-	case cat == mimecat.Picture:
+	case mimecat.Video:
+		a.file.Taken = a.mediainfo.Taken
+		if l := a.mediainfo.Location; l != nil {
+			a.location = &database.Location{Latitude: l.Latitude, Longitude: l.Longitude, Altitude: l.Altitude}
+		}
+	}
+	return a, nil
+}
+
+// storeFile adds the analyzed file to the database. db.BeginTx must
+// have been called before.
+func storeFile(db database.DB, a addition) error {
+	var err error
+	switch a.category {
+	case mimecat.Other:
+		if err = db.AddFile(a.file); err != nil {
+			return fmt.Errorf("could not add other file '%s': %s", a.path, err)
+		}
+	case mimecat.Picture:
 		if err = addPicture(a, db); err != nil {
-			return fmt.Errorf("could not add picture '%s': %s", path, err)
+			return fmt.Errorf("could not add picture '%s': %s", a.path, err)
 		}
-	case cat == mimecat.Video && a.mediainfo.Type != mediainfo.TypeAudio:
+	case mimecat.Video:
 		if err = addVideo(a, db); err != nil {
-			return fmt.Errorf("could not add video '%s': %s", path, err)
+			return fmt.Errorf("could not add video '%s': %s", a.path, err)
 		}
-	case cat == mimecat.Video && a.mediainfo.Type == mediainfo.TypeAudio:
-		fallthrough
-	case cat == mimecat.Audio:
+	case mimecat.Audio:
 		if err = addAudio(a, db); err != nil {
-			return fmt.Errorf("could not add audio '%s': %s", path, err)
+			return fmt.Errorf("could not add audio '%s': %s", a.path, err)
 		}
-	case cat == mimecat.Document:
+	case mimecat.Document:
 		if err = addDocument(a, db); err != nil {
-			return fmt.Errorf("could not add document '%s': %s", path, err)
+			return fmt.Errorf("could not add document '%s': %s", a.path, err)
+		}
+	case mimecat.Executable:
+		if err = addExecutable(a, db); err != nil {
+			return fmt.Errorf("could not add executable '%s': %s", a.path, err)
+		}
+	case mimecat.Archive:
+		arc := &database.Archive{File: a.file, Members: a.members}
+		if err = db.AddArchive(arc); err != nil {
+			return fmt.Errorf("could not add archive '%s': %s", a.path, err)
 		}
 	}
 	return nil
 }
 
 func determineMIME(path string) (string, error) {
-	var mimeType string
 	f, err := os.Open(path)
 	if err != nil {
 		return "", fmt.Errorf("could not open file '%s': %s", path, err)
@@ -164,12 +217,41 @@ func determineMIME(path string) (string, error) {
 	defer f.Close()
 	buf := make([]byte, 512)
 	n, _ := f.Read(buf)
-	mimeType = http.DetectContentType(buf[:n])
+	return detectMIME(buf[:n], path), nil
+}
+
+// magicNumbers maps the magic numbers of formats, that are not
+// recognized by http.DetectContentType, to their MIME types.
+var magicNumbers = []struct {
+	offset int
+	magic  string
+	mime   string
+}{
+	{0, "\xFD7zXZ\x00", "application/x-xz"},
+	{0, "7z\xBC\xAF\x27\x1C", "application/x-7z-compressed"},
+	{0, "BZh", "application/x-bzip2"},
+	{0, "\x28\xB5\x2F\xFD", "application/zstd"},
+	{257, "ustar", "application/x-tar"},
+	{0, "\x7FELF", "application/x-executable"},
+}
+
+// detectMIME determines the MIME type of a file from the first 512
+// bytes of its content and its path.
+func detectMIME(head []byte, path string) string {
+	mimeType := http.DetectContentType(head)
 	if idx := strings.IndexByte(mimeType, ';'); idx != -1 {
 		mimeType = mimeType[:idx]
 	}
 	ext := filepath.Ext(path)
-	if n == 0 || mimeType == "application/octet-stream" {
+	if mimeType == "application/octet-stream" {
+		for _, m := range magicNumbers {
+			if len(head) >= m.offset+len(m.magic) &&
+				string(head[m.offset:m.offset+len(m.magic)]) == m.magic {
+				return m.mime
+			}
+		}
+	}
+	if len(head) == 0 || mimeType == "application/octet-stream" {
 		secondOpinion := mime.TypeByExtension(ext)
 		if secondOpinion != "" {
 			mimeType = secondOpinion
@@ -187,7 +269,7 @@ func determineMIME(path string) (string, error) {
 		case ".ods":
 			mimeType = "application/vnd.oasis.opendocument.spreadsheet"
 		case ".odp":
-			mimeType = "application/vnd.oasis.opendocument.presentation "
+			mimeType = "application/vnd.oasis.opendocument.presentation"
 		case ".docx":
 			mimeType = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
 		case ".xlsx":
@@ -196,66 +278,115 @@ func determineMIME(path string) (string, error) {
 			mimeType = "application/vnd.openxmlformats-officedocument.presentationml.presentation"
 		}
 	}
-	return mimeType, nil
-}
-
-func addFile(a addition, db database.DB) error {
-	f, err := toFile(a)
-	if err != nil {
-		return err
-	}
-	return db.AddFile(f)
+	return mimeType
 }
 
 func addPicture(a addition, db database.DB) error {
-	f, err := toFile(a)
-	if err != nil {
-		return err
-	}
 	pic := &database.Picture{
-		File:   f,
-		Camera: a.mediainfo.Camera,
+		File:     a.file,
+		Camera:   a.mediainfo.Camera,
+		Location: a.location,
+		Width:    a.mediainfo.Width,
+		Height:   a.mediainfo.Height,
+
+		PerceptualHash: a.phash,
 	}
 	return db.AddPicture(pic)
 }
 
 func addVideo(a addition, db database.DB) error {
-	f, err := toFile(a)
-	if err != nil {
-		return err
-	}
 	vid := &database.Video{
-		File:     f,
-		Duration: a.mediainfo.Duration,
-		Camera:   a.mediainfo.Camera,
-		Year:     a.mediainfo.Year,
+		File:       a.file,
+		Duration:   a.mediainfo.Duration,
+		Camera:     a.mediainfo.Camera,
+		Year:       a.mediainfo.Year,
+		Location:   a.location,
+		Width:      a.mediainfo.Width,
+		Height:     a.mediainfo.Height,
+		FrameRate:  a.mediainfo.FrameRate,
+		VideoCodec: a.mediainfo.VideoCodec,
+		AudioCodec: a.mediainfo.AudioCodec,
+		Bitrate:    a.mediainfo.Bitrate,
 	}
 	return db.AddVideo(vid)
 }
 
 func addAudio(a addition, db database.DB) error {
-	f, err := toFile(a)
-	if err != nil {
-		return err
+	audio := &database.Audio{
+		File:        a.file,
+		Duration:    a.mediainfo.Duration,
+		Author:      a.mediainfo.Author,
+		Year:        a.mediainfo.Year,
+		AlbumArtist: a.mediainfo.AlbumArtist,
+		Album:       a.mediainfo.Album,
+		Title:       a.mediainfo.Title,
+		Genre:       a.mediainfo.Genre,
+		Track:       a.mediainfo.Track,
+		Disc:        a.mediainfo.Disc,
+		Codec:       a.mediainfo.AudioCodec,
+		Bitrate:     a.mediainfo.Bitrate,
 	}
-	pic := &database.Audio{
-		File:     f,
-		Duration: a.mediainfo.Duration,
-		Author:   a.mediainfo.Author,
-		Year:     a.mediainfo.Year,
+	return db.AddAudio(audio)
+}
+
+func addExecutable(a addition, db database.DB) error {
+	exe := &database.Executable{
+		File:        a.file,
+		Arch:        a.executable.Arch,
+		Linking:     a.executable.Linking,
+		Interpreter: a.executable.Interpreter,
 	}
-	return db.AddAudio(pic)
+	return db.AddExecutable(exe)
+}
+
+// isExecutable returns true if any exec bit of the file is set.
+func isExecutable(d fs.DirEntry) bool {
+	info, err := d.Info()
+	return err == nil && info.Mode().Perm()&0111 != 0
 }
 
 func addDocument(a addition, db database.DB) error {
-	f, err := toFile(a)
-	if err != nil {
-		return err
+	doc := &database.Document{
+		File: a.file,
+		Text: a.text,
 	}
-	pic := &database.Document{
-		File: f,
+	return db.AddDocument(doc)
+}
+
+// toMembers converts the members of the archive at path to entries of
+// the matching categories. Their content is not analyzed any further.
+func toMembers(path string, members []archive.Member) []database.Entry {
+	entries := make([]database.Entry, 0, len(members))
+	for _, m := range members {
+		f := &database.File{
+			Path:         path + "!/" + m.Name,
+			Size:         m.Size,
+			CreatedGuess: m.Modified,
+			Modified:     m.Modified,
+			MIME:         detectMIME(m.Head, m.Name),
+		}
+		if interpreter, ok := executable.Interpreter(m.Head); ok {
+			entries = append(entries, &database.Executable{File: f, Interpreter: interpreter})
+			continue
+		}
+		switch mimecat.MIMEToCategory(f.MIME) {
+		case mimecat.Picture:
+			entries = append(entries, &database.Picture{File: f})
+		case mimecat.Video:
+			entries = append(entries, &database.Video{File: f})
+		case mimecat.Audio:
+			entries = append(entries, &database.Audio{File: f})
+		case mimecat.Document:
+			entries = append(entries, &database.Document{File: f})
+		case mimecat.Archive:
+			entries = append(entries, &database.Archive{File: f})
+		case mimecat.Executable:
+			entries = append(entries, &database.Executable{File: f})
+		default:
+			entries = append(entries, f)
+		}
 	}
-	return db.AddDocument(pic)
+	return entries
 }
 
 func toFile(a addition) (*database.File, error) {
@@ -0,0 +1,77 @@
+package den
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/codesoap/den/database"
+)
+
+// writeZip writes a zip archive with members of the given names to path.
+func writeZip(t *testing.T, path string, modified time.Time, names ...string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := zip.NewWriter(f)
+	for _, name := range names {
+		m, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = m.Write([]byte("content of " + name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chtimes(path, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestArchiveMembers(t *testing.T) {
+	db := newTestDB(t)
+	dir := t.TempDir()
+	arc := filepath.Join(dir, "a.zip")
+	writeZip(t, arc, time.Unix(1_000_000, 0), "docs/x.txt", "y.txt")
+	track(t, db, dir)
+	want := []string{arc, arc + "!/docs/x.txt", arc + "!/y.txt"}
+	if got := indexedPaths(t, db, dir); !slices.Equal(got, want) {
+		t.Errorf("indexed %q, want %q", got, want)
+	}
+
+	// Reindexing the changed archive replaces its members:
+	writeZip(t, arc, time.Unix(2_000_000, 0), "z.txt")
+	rescan(t, db)
+	want = []string{arc, arc + "!/z.txt"}
+	if got := indexedPaths(t, db, dir); !slices.Equal(got, want) {
+		t.Errorf("indexed %q after changing the archive, want %q", got, want)
+	}
+
+	if err := os.Remove(arc); err != nil {
+		t.Fatal(err)
+	}
+	rescan(t, db)
+	if got := indexedPaths(t, db, dir); len(got) != 0 {
+		t.Errorf("indexed %q after removing the archive, want nothing", got)
+	}
+}
+
+func indexedPaths(t *testing.T, db database.DB, path string) []string {
+	t.Helper()
+	var paths []string
+	for _, f := range indexed(t, db, path) {
+		paths = append(paths, f.Path)
+	}
+	return paths
+}
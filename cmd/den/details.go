@@ -0,0 +1,265 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/codesoap/den/database"
+)
+
+// printCreatedYears prints the number of files per year of creation.
+// The line format receives the year twice, followed by the count.
+func printCreatedYears(header, line string, details database.FileDetails) {
+	for i, c := range details.CreatedYears {
+		if i == 0 {
+			fmt.Println(header)
+		}
+		fmt.Printf(line, c.Year, c.Year, c.Count)
+	}
+}
+
+// printTags prints the number of files per tag. The line format
+// receives the tag, followed by the count.
+func printTags(header, line string, details database.FileDetails) {
+	for i, c := range details.Tags {
+		if i == 0 {
+			fmt.Println(header)
+		}
+		fmt.Printf(line, c.Value, c.Count)
+	}
+}
+
+// printOrientations prints the number of files per orientation. The
+// line format receives the orientation twice, followed by the count.
+func printOrientations(header, line string, counts []database.ValueCount) {
+	for i, c := range counts {
+		if i == 0 {
+			fmt.Println(header)
+		}
+		fmt.Printf(line, c.Value, c.Value, c.Count)
+	}
+}
+
+// printResolutions prints the number of files per minimum resolution.
+// The line format receives the resolution twice, followed by the count.
+func printResolutions(header, line string, counts []database.ResolutionCount) {
+	for i, c := range counts {
+		if i == 0 {
+			fmt.Println(header)
+		}
+		fmt.Printf(line, c.Resolution, c.Resolution, c.Count)
+	}
+}
+
+// printSizes prints the number of files per range of sizes. The line
+// format receives the flags selecting a range, followed by the count.
+func printSizes(header, line string, details database.FileDetails) {
+	for i, c := range details.Sizes {
+		if i == 0 {
+			fmt.Println(header)
+		}
+		var flags []string
+		if c.Min > 0 {
+			flags = append(flags, "-minsize "+formatDecimalSize(c.Min))
+		}
+		if c.Max != nil {
+			flags = append(flags, "-maxsize "+formatDecimalSize(*c.Max))
+		}
+		fmt.Printf(line, strings.Join(flags, " "), c.Count)
+	}
+}
+
+// formatDecimalSize formats a number of bytes, that is a power of ten,
+// with the units accepted by -minsize and -maxsize.
+func formatDecimalSize(bytes int64) string {
+	const units = "KMGT"
+	i := -1
+	for bytes >= 1000 && bytes%1000 == 0 && i < len(units)-1 {
+		bytes /= 1000
+		i++
+	}
+	if i < 0 {
+		return strconv.FormatInt(bytes, 10)
+	}
+	return fmt.Sprintf("%d%c", bytes, units[i])
+}
+
+func printPicturesDetails(details database.PictureDetails) {
+	printCreatedYears("Pictures can be filtered by the year of creation:",
+		"\t`-c %d` lists all pictures created in %d (%d pictures).\n",
+		details.FileDetails)
+	printSizes("Pictures can be filtered by their size:",
+		"\t`%s` lists all pictures in that size range (%d pictures).\n",
+		details.FileDetails)
+	for i, c := range details.Cameras {
+		if i == 0 {
+			fmt.Println("Pictures can be filtered by the camera they were created with:")
+		}
+		f := "\t`-camera '%s'` lists all pictures created with that camera (%d pictures).\n"
+		fmt.Printf(f, c.Value, c.Count)
+	}
+	if details.Geotagged > 0 {
+		fmt.Println("Pictures can be filtered by the location they were taken at:")
+		f := "\t`-geo` lists all pictures with a known location (%d pictures).\n"
+		fmt.Printf(f, details.Geotagged)
+	}
+	printOrientations("Pictures can be filtered by their orientation:",
+		"\t`-orientation %s` lists all %s pictures (%d pictures).\n",
+		details.Orientations)
+	printResolutions("Pictures can be filtered by their resolution:",
+		"\t`-minres %dp` lists all pictures with a resolution of at least %dp (%d pictures).\n",
+		details.Resolutions)
+	printTags("Pictures can be filtered by tag:",
+		"\t`-tag '%s'` lists all pictures with that tag (%d pictures).\n",
+		details.FileDetails)
+}
+
+func printExecutablesDetails(details database.ExecutableDetails) {
+	printCreatedYears("Executables can be filtered by the year of creation:",
+		"\t`-c %d` lists all executables created in %d (%d executables).\n",
+		details.FileDetails)
+	printSizes("Executables can be filtered by their size:",
+		"\t`%s` lists all executables in that size range (%d executables).\n",
+		details.FileDetails)
+	for i, c := range details.Archs {
+		if i == 0 {
+			fmt.Println("Executables can be filtered by their architecture:")
+		}
+		f := "\t`-arch '%s'` lists all executables built for that architecture (%d executables).\n"
+		fmt.Printf(f, c.Value, c.Count)
+	}
+	for i, c := range details.Interpreters {
+		if i == 0 {
+			fmt.Println("Executables can be filtered by their interpreter:")
+		}
+		f := "\t`-interp '%s'` lists all executables run by that interpreter (%d executables).\n"
+		fmt.Printf(f, c.Value, c.Count)
+	}
+	printTags("Executables can be filtered by tag:",
+		"\t`-tag '%s'` lists all executables with that tag (%d executables).\n",
+		details.FileDetails)
+}
+
+func printVideosDetails(details database.VideoDetails) {
+	printCreatedYears("Videos can be filtered by the year of file creation:",
+		"\t`-c %d` lists all videos created in %d (%d videos).\n",
+		details.FileDetails)
+	printSizes("Videos can be filtered by their size:",
+		"\t`%s` lists all videos in that size range (%d videos).\n",
+		details.FileDetails)
+	fmt.Printf("Videos can be filtered by their length:\n")
+	fmt.Printf("\t`-durmax 10m` lists all videos up to 10 minutes long (%d videos).\n", details.Short)
+	fmt.Printf("\t`-durmin 10m` lists all videos at least 10 minutes long (%d videos).\n", details.Long)
+	for i, c := range details.Cameras {
+		if i == 0 {
+			fmt.Println("Videos can be filtered by the camera they were created with:")
+		}
+		f := "\t`-camera '%s'` lists all videos created with that camera (%d videos).\n"
+		fmt.Printf(f, c.Value, c.Count)
+	}
+	for i, c := range details.RecordedDecades {
+		if i == 0 {
+			fmt.Println("Videos can be filtered by the year they were recorded:")
+		}
+		f := "\t`-year %d-%d` lists all videos recorded in that decade (%d videos).\n"
+		fmt.Printf(f, c.Year, c.Year+9, c.Count)
+	}
+	if details.Geotagged > 0 {
+		fmt.Println("Videos can be filtered by the location they were recorded at:")
+		f := "\t`-geo` lists all videos with a known location (%d videos).\n"
+		fmt.Printf(f, details.Geotagged)
+	}
+	printOrientations("Videos can be filtered by their orientation:",
+		"\t`-orientation %s` lists all %s videos (%d videos).\n",
+		details.Orientations)
+	printResolutions("Videos can be filtered by their resolution:",
+		"\t`-minres %dp` lists all videos with a resolution of at least %dp (%d videos).\n",
+		details.Resolutions)
+	if len(details.VideoCodecs) > 0 || len(details.AudioCodecs) > 0 {
+		fmt.Println("Videos can be filtered by their codecs:")
+	}
+	for _, c := range details.VideoCodecs {
+		f := "\t`-codec '%s'` lists all videos with that video codec (%d videos).\n"
+		fmt.Printf(f, c.Value, c.Count)
+	}
+	for _, c := range details.AudioCodecs {
+		f := "\t`-codec '%s'` lists all videos with that audio codec (%d videos).\n"
+		fmt.Printf(f, c.Value, c.Count)
+	}
+	for i, c := range details.FrameRates {
+		if i == 0 {
+			fmt.Println("Videos can be filtered by their frame rate:")
+		}
+		f := "\t`-fps %s` lists all videos with ca. %s frames per second (%d videos).\n"
+		fmt.Printf(f, c.Value, c.Value, c.Count)
+	}
+	printTags("Videos can be filtered by tag:",
+		"\t`-tag '%s'` lists all videos with that tag (%d videos).\n",
+		details.FileDetails)
+}
+
+func printAudiosDetails(details database.AudioDetails) {
+	printCreatedYears("Audio files can be filtered by the year of file creation:",
+		"\t`-c %d` lists all audio files created in %d (%d files).\n",
+		details.FileDetails)
+	printSizes("Audio files can be filtered by their size:",
+		"\t`%s` lists all audio files in that size range (%d files).\n",
+		details.FileDetails)
+	fmt.Printf("Audio files can be filtered by their length:\n")
+	fmt.Printf("\t`-durmax 10m` lists all audio files up to 10 minutes long (%d files).\n", details.Short)
+	fmt.Printf("\t`-durmin 10m` lists all audio files at least 10 minutes long (%d files).\n", details.Long)
+	for i, c := range details.Authors {
+		if i == 0 {
+			fmt.Println("Audio files can be filtered by their author:")
+		} else if i == 8 {
+			fmt.Println("\t...")
+			break
+		}
+		f := "\t`-author '%s'` lists all files created by that author (%d files).\n"
+		fmt.Printf(f, c.Value, c.Count)
+	}
+	for i, c := range details.RecordedDecades {
+		if i == 0 {
+			fmt.Println("Audio files can be filtered by the year they were recorded:")
+		}
+		f := "\t`-year %d-%d` lists all files recorded in that decade (%d files).\n"
+		fmt.Printf(f, c.Year, c.Year+9, c.Count)
+	}
+	artists := 0
+	for i, c := range details.Albums {
+		if i == 0 {
+			fmt.Println("Audio files can be filtered by their album:")
+		}
+		if i == 0 || c.Artist != details.Albums[i-1].Artist {
+			if artists++; artists > 8 {
+				fmt.Println("\t...")
+				break
+			}
+			artist := c.Artist
+			if artist == "" {
+				artist = "Unknown artist"
+			}
+			fmt.Printf("\t%s:\n", artist)
+		}
+		f := "\t\t`-album '%s'` lists all files of that album (%d files).\n"
+		fmt.Printf(f, c.Album, c.Count)
+	}
+	for i, c := range details.Genres {
+		if i == 0 {
+			fmt.Println("Audio files can be filtered by their genre:")
+		}
+		f := "\t`-genre '%s'` lists all files of that genre (%d files).\n"
+		fmt.Printf(f, c.Value, c.Count)
+	}
+	for i, c := range details.Codecs {
+		if i == 0 {
+			fmt.Println("Audio files can be filtered by their codec:")
+		}
+		f := "\t`-codec '%s'` lists all files with that codec (%d files).\n"
+		fmt.Printf(f, c.Value, c.Count)
+	}
+	printTags("Audio files can be filtered by tag:",
+		"\t`-tag '%s'` lists all audio files with that tag (%d files).\n",
+		details.FileDetails)
+}
@@ -4,65 +4,156 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"math"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
 	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"text/template"
 	"time"
 
 	"github.com/codesoap/den"
 	"github.com/codesoap/den/database"
+	"github.com/codesoap/den/internal/ignore"
+	"github.com/codesoap/den/internal/units"
 )
 
 var (
 	db database.DB
 
-	createdFromYear, createdUntilYear   *int
+	createdSince, createdUntil          *time.Time
+	modifiedSince, modifiedUntil        *time.Time
+	takenSince, takenUntil              *time.Time
 	dFlag                               bool
+	nameFlag                            string
 	cameraFlag                          string
+	nearFlag                            *database.Area
+	geoFlag                             bool
+	minresFlag                          *int
+	minsizeFlag, maxsizeFlag            *int64
+	orientationFlag, codecFlag          string
+	minFPS, maxFPS                      *float64
 	durminFlag, durmaxFlag              *time.Duration
 	recordedFromYear, recordedUntilYear *int
 	authorFlag                          string
+	albumFlag, titleFlag, genreFlag     string
 	txtFlag                             bool
+	textFlag                            string
+	categoryFlag                        string
+	similarFlag                         bool
+	explainFlag                         bool
+	thresholdFlag                       int
+	tagFlags                            tagList
+	ignoreFlags                         patternList
+	hiddenFlag, followFlag, xdevFlag    bool
+	lFlag                               bool
+	dbFlag, profileFlag                 string
+	sortFlag                            string
+	reverseFlag                         bool
+	limitFlag, randomFlag               int
+	inArchiveFlag                       bool
+	archFlag, interpFlag                string
+	jobsFlag                            int
+	outputFormat                        format
+	outputTemplate                      *template.Template
 )
 
 func init() {
 	log.SetFlags(0)
-	initDB()
 	parseFlags()
+	initDB()
 }
 
 func initDB() {
-	home, err := os.UserHomeDir()
+	dbPath, err := databasePath()
 	if err != nil {
 		log.Fatalln("Could not find directory for database:", err)
 	}
-	// FIXME: Use more conventional dir on Windows.
-	dbDir := filepath.Join(home, ".local", "share", "den")
-	if err = os.MkdirAll(dbDir, 0755); err != nil {
+	if err = os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
 		log.Fatalln("Could not create database directory:", err)
 	}
-	dbPath := filepath.Join(dbDir, "db.sqlite3")
-	db, err = database.NewDB(dbPath)
+	db, err = database.OpenDB(dbPath)
 	if err != nil {
 		log.Fatalln("Could not get database:", err)
 	}
+	if flag.Arg(0) != "db" {
+		// The db command updates the schema only when asked to.
+		migrate()
+	}
+}
+
+// migrate updates the schema of the database, if necessary.
+func migrate() {
+	backup, err := db.Migrate()
+	if backup != "" {
+		fmt.Fprintf(os.Stderr, "Saved a backup of the database to '%s' before updating its schema.\n", backup)
+	}
+	if err != nil {
+		log.Fatalln("Could not update database schema:", err)
+	}
+}
+
+// databasePath returns the path of the database selected with -db,
+// -profile or DEN_DB, in this order of precedence. By default, the
+// database is stored in the data directory of the user.
+func databasePath() (string, error) {
+	switch {
+	case dbFlag != "":
+		return filepath.Abs(dbFlag)
+	case profileFlag != "":
+		dir, err := dataDir()
+		return filepath.Join(dir, "den", "profiles", profileFlag+".sqlite3"), err
+	case os.Getenv("DEN_DB") != "":
+		return filepath.Abs(os.Getenv("DEN_DB"))
+	}
+	dir, err := dataDir()
+	return filepath.Join(dir, "den", "db.sqlite3"), err
+}
+
+// dataDir returns the directory for user specific data files, according
+// to the XDG Base Directory Specification or the conventions of
+// Windows.
+func dataDir() (string, error) {
+	if runtime.GOOS == "windows" {
+		if dir := os.Getenv("LocalAppData"); dir != "" {
+			return dir, nil
+		}
+		return "", fmt.Errorf("%%LocalAppData%% is not defined")
+	}
+	if dir := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dir) {
+		// Relative paths are invalid according to the specification.
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share"), nil
 }
 
 func parseFlags() {
 	flag.Usage = func() {
 		fmt.Fprint(os.Stderr, `Usage:
-    den (t|track) <PATH>
+    den [-j <N>] [-hidden] [-follow-symlinks] [-xdev] [-ignore <PATTERN>]...
+        (t|track) <PATH>
         Track all files within PATH.
-    den (l|list)
-        List tracked paths.
+    den [-l] (l|list)
+        List tracked paths. With -l, the options they were tracked with
+        are listed too, as well as the number of their ignore patterns.
     den (u|untrack) <PATH>
     	Stop tracking PATH.
-    den (r|rescan)
+    den [-j <N>] (r|rescan) [<PATH>...]
     	Update database for deleted, changed or added files within all
-    	tracked paths.
+    	tracked paths. If paths are given, only the files within them are
+    	checked; they must be tracked paths or directories within one.
+    den (w|watch)
+    	Keep updating the database while files within tracked paths are
+    	changed, until interrupted. Only available on Linux. Run rescan
+    	first, to catch up on changes from before watching.
     den [-d] [FILTER...] (p|picture) [<PREFIX>]
         Print the paths of tracked pictures.
     den [-d] [FILTER...] (v|video) [<PREFIX>]
@@ -71,26 +162,169 @@ func parseFlags() {
         Print the paths of tracked audio files.
     den [-d] [FILTER...] (d|document) [<PREFIX>]
         Print the paths of tracked documents.
+    den [-d] [FILTER...] (ar|archive) [<PREFIX>]
+        Print the paths of tracked archives.
+    den [-d] [FILTER...] (x|executable) [<PREFIX>]
+        Print the paths of tracked executables: binaries, scripts and
+        otherwise uncategorized files with the exec bit set.
     den [-d] [FILTER...] (o|other) [<PREFIX>]
         Print the paths of tracked other files.
     den [-d] [FILTER...] all [<PREFIX>]
         Print the paths of tracked files, regardless of category.
+    den [-explain] [FILTER...] query <EXPRESSION>
+        Print the paths of tracked files matching EXPRESSION, e.g.
+        '(camera:Canon OR camera:iPhone) AND NOT path:~/Pictures/scans
+        AND taken in 2019..2021'. See "Query expressions" below.
+    den [-category <CATEGORY>] [-similar] [FILTER...] dupes [<PREFIX>]
+        Print groups of files with identical content, separated by empty
+        lines. The groups wasting the most space are printed first.
+    den [-threshold <N>] similar <FILE>
+        Print the paths of tracked pictures that look like FILE, even if
+        they were resized or recompressed. The most similar pictures are
+        printed first.
+    den tag (add|rm) <TAG> <FILE>...
+        Add TAG to or remove it from the given tracked files. Tags are
+        kept when a file is reindexed.
+    den tag list [<FILE>...]
+        Print the tags of the given files, or of all files, together with
+        the number of files carrying them.
+    den ignore (add|rm) <PATH> <PATTERN>...
+        Add ignore patterns to or remove them from the tracked PATH. The
+        change takes effect with the next rescan, which also removes
+        files that became ignored from the database.
+    den ignore list [<PATH>]
+        Print the ignore patterns of PATH, or of all tracked paths.
+    den db migrate [-n]
+        Update the schema of the database, which otherwise happens when
+        it is first used by a new version of den. With -n, the pending
+        updates are only printed.
+
+    Within tracked paths, only non-hidden files are considered, unless
+    the path was tracked with -hidden. Files can also be ignored with
+    patterns in the syntax of gitignore, which are read from .denignore
+    files in any directory and from the ignore patterns of the tracked
+    path. When printing files, paths will be sorted by modification
+    date, last modified first, unless another order is selected with
+    -sort or -random.
 
-    Within tracked paths, only non-hidden files are considered. When
-    printing files, paths will be sorted by modification date, last
-    modified first.
+    The text filters -camera, -codec, -author, -album, -title, -genre,
+    -arch and -interp match values equal to the given text, ignoring
+    case. '~TEXT' matches values containing TEXT, ignoring case, and
+    '/REGEX/' matches values with the regular expression REGEX, e.g.
+    '/^(canon|nikon)/', which also ignores case, unless REGEX starts
+    with flags like '(?-i)'. Text containing *, ? or [ is a glob
+    pattern, like 'iphone*'. If nothing matches a text filter, similar
+    values are suggested.
 
     <PREFIX> is an optional filter for the path. E.g. 'den all .' will
     only list files in the current directory.
+
+    The database is stored in $XDG_DATA_HOME/den/db.sqlite3, which
+    defaults to ~/.local/share/den/db.sqlite3, or in
+    %LocalAppData%\den\db.sqlite3 on Windows. Another database can be
+    used with the -db or -profile options, or by setting the DEN_DB
+    environment variable to its path. Before the schema of an existing
+    database is updated, a copy of it is saved next to it, e.g. as
+    db.sqlite3.v14.bak for a database with schema version 14.
 Options:
     -d
     	Show details about the gathered metadata for the given file type.
+    -j <N>
+        The number of files to analyze concurrently when tracking or
+        rescanning. Defaults to the number of CPUs.
+    -hidden
+        Also track hidden files within the newly tracked path.
+    -follow-symlinks
+        Follow symbolic links within the newly tracked path. Links to
+        directories that are tracked already are skipped.
+    -xdev
+        Skip directories on other file systems than the newly tracked
+        path, like mount points. Not available on Windows.
+    -ignore <PATTERN>
+        A pattern in the syntax of gitignore, e.g. 'node_modules/' or
+        '*.iso', for files to ignore within the newly tracked path. Can
+        be given multiple times.
+    -l
+        Also list the options of the tracked paths.
+    -db <PATH>
+        The database to use. It is created if it does not exist yet.
+    -profile <NAME>
+        Use a separate database with the given name, e.g. to keep the
+        index of an external drive apart. The databases of profiles are
+        stored in the profiles directory next to the default database.
+    -format <FORMAT>
+        The output format when listing files. Valid formats are paths
+        (the default), null (NUL-terminated paths), json, ndjson and csv.
+        The structured formats include the metadata of the listed
+        category. A Go template like '{{.Size}} {{.Path}}' can also be
+        given; it is executed for every file.
+    -sort <KEY>
+        The order of listed files. Valid keys are modified (the default),
+        created, size, path, name, duration and taken. Times, sizes and
+        durations are listed in descending order, paths and names in
+        ascending order. Full-text searches with -text list the best
+        matches first, unless a key is given.
+    -reverse
+        Reverse the order of listed files.
+    -limit <N>
+        List at most N files.
+    -random <N>
+        List N randomly chosen files in random order.
+    -similar
+        Let dupes group pictures that look alike, instead of files with
+        identical content. Only JPEG, PNG and GIF pictures are compared.
+    -explain
+        Print the SQL query and its arguments, that the query command
+        would run, instead of running it.
+    -threshold <N>
+        The number of bits, in which the perceptual hashes of pictures
+        that look alike may differ, from 0 to 64. Defaults to 10.
 Filters:
-    -c <YEAR>
-        The year in which a file was created. Ranges like 1990-1999 are also
-        acceptable.
+    -name <PATTERN>
+        Text contained in the name of a file, ignoring case, e.g. 'IMG_20'.
+        Glob patterns like '*.jp*g' and regular expressions like
+        '/^report-\d+\.pdf$/' are also acceptable. Names are searched
+        with an index, so this replaces locate for tracked paths.
+    -c <DATE>
+        The date a file was created, e.g. 2019, 2019-05 or 2019-05-17.
+        Ranges like 2019-05..2019-08 or 1990-1999 are also acceptable,
+        as well as open ranges like 2024-03.. or ..2019. Relative dates
+        are today, yesterday, weekdays like monday, which refer to the
+        last such day, and durations like 12h, 7d, 2w, 3mo or 1y, which
+        refer to the time since then. 'since <DATE>' includes everything
+        from DATE until now. For pictures and videos, the time they were
+        taken is used instead, if it is known.
+    -m <DATE>
+        The date a file was last modified, given like for -c.
+    -taken <DATE>
+        The date a picture or video was taken according to its metadata,
+        given like for -c.
+    -minsize <SIZE>
+        The minimum size of a file, e.g. 500K, 10M or 2GiB. K, M, G and T
+        are powers of 1000, while KiB, MiB, GiB and TiB are powers of
+        1024.
+    -maxsize <SIZE>
+        The maximum size of a file, given like for -minsize.
     -camera <CAMERA>
     	The camera a video or picture has been taken with.
+    -near <LAT,LON,RADIUS>
+        The location a video or picture has been taken at, given in
+        decimal degrees and a radius like 500m or 2km, e.g.
+        '48.8584,2.2945,1km'.
+    -geo
+        Show only videos or pictures with a known location.
+    -minres <RESOLUTION>
+        The minimum resolution of a video or picture, given as the length
+        of its shorter side, e.g. 720p, 1080p or 4k.
+    -orientation <ORIENTATION>
+        The orientation of a video or picture: portrait, landscape or
+        square.
+    -codec <CODEC>
+        The video or audio codec of a video or audio file, e.g. hevc, avc
+        or aac.
+    -fps <FPS>
+        The frame rate of a video, rounded to the given precision, e.g.
+        30 or 29.97. Ranges like 24-30 are also acceptable.
     -durmin <DURATION>
     	The minimum duration of a video or audio file, e.g. 10m or 30s.
     -durmax <DURATION>
@@ -100,44 +334,193 @@ Filters:
     	1990-1999 are also acceptable.
     -author <AUTHOR>
     	The author (e.g. band) of an audio file.
+    -album <ALBUM>
+        The album of an audio file.
+    -title <TITLE>
+        The title of an audio file, e.g. the name of a song.
+    -genre <GENRE>
+        The genre of an audio file.
+    -arch <ARCH>
+        The architecture an executable was built for, e.g. amd64 or arm64.
+    -interp <INTERPRETER>
+        The interpreter of a script, e.g. python3 or bash, or the dynamic
+        loader of a binary.
     -txt
         Show only plain text files when listing documents. These are files
         suitable for editing with a text editor.
+    -text <QUERY>
+        Show only documents containing the given text, best matches first.
+        A snippet of the matching text is printed below each path. QUERY
+        uses the SQLite FTS5 syntax, e.g. 'acme AND invoice' or 'inv*'.
+    -tag <TAG>
+        Show only files with the given tag. If given multiple times, files
+        must carry all tags. Files carrying TAG are excluded instead, if
+        it is prefixed with an exclamation mark, e.g. '!todo'.
+    -inarchive
+        Also show files within zip and tar archives. Their paths consist
+        of the path of the archive, followed by '!/' and their path
+        within the archive, e.g. '/backup.zip!/photos/img.jpg'.
+    -category <CATEGORY>
+        Show only duplicates of the given category. Valid categories are
+        picture, video, audio, document, archive, executable and other.
+Query expressions:
+    An expression consists of comparisons like 'duration>10m', which are
+    combined with AND, OR, NOT and parentheses. AND may be omitted.
+    Values containing spaces, parentheses or operators must be enclosed
+    in double quotes, e.g. 'album="Live at Pompeii"'. The operators are:
+        FIELD:VALUE      VALUE is contained in FIELD, ignoring case. Paths
+                         must be VALUE or lie below it and dates lie
+                         within it. Values containing *, ? or [ are
+                         glob patterns and /REGEX/ is a regular
+                         expression.
+        FIELD=VALUE      FIELD equals VALUE.
+        FIELD!=VALUE     FIELD does not equal VALUE or is unknown.
+        FIELD<VALUE      Also <=, > and >=, for numbers, sizes, dates
+                         and durations.
+        FIELD in A..B    FIELD lies between A and B. Either may be
+                         omitted.
+    The fields are category (picture, video, audio, document, archive,
+    executable or other), path, name, mime, tag, size, modified, created,
+    taken, duration, year, camera, width, height, resolution, fps, codec,
+    bitrate, author, album, title, genre, arch and interpreter. Values
+    are given like for the corresponding filters.
 `)
 		os.Exit(1)
 	}
 	cFlag := flag.String("c", "", "")
+	mFlag := flag.String("m", "", "")
+	takenFlag := flag.String("taken", "", "")
 	flag.BoolVar(&dFlag, "d", false, "")
+	flag.IntVar(&jobsFlag, "j", runtime.NumCPU(), "")
+	flag.BoolVar(&hiddenFlag, "hidden", false, "")
+	flag.BoolVar(&followFlag, "follow-symlinks", false, "")
+	flag.BoolVar(&xdevFlag, "xdev", false, "")
+	flag.Var(&ignoreFlags, "ignore", "")
+	flag.BoolVar(&lFlag, "l", false, "")
+	flag.StringVar(&dbFlag, "db", "", "")
+	flag.StringVar(&profileFlag, "profile", "", "")
+	flag.StringVar(&sortFlag, "sort", "", "")
+	flag.BoolVar(&reverseFlag, "reverse", false, "")
+	flag.IntVar(&limitFlag, "limit", 0, "")
+	flag.IntVar(&randomFlag, "random", 0, "")
+	formatFlag := flag.String("format", "paths", "")
+	flag.StringVar(&nameFlag, "name", "", "")
 	flag.StringVar(&cameraFlag, "camera", "", "")
+	nearFlagValue := flag.String("near", "", "")
+	flag.BoolVar(&geoFlag, "geo", false, "")
+	minresFlagValue := flag.String("minres", "", "")
+	minsizeFlagValue := flag.String("minsize", "", "")
+	maxsizeFlagValue := flag.String("maxsize", "", "")
+	flag.StringVar(&orientationFlag, "orientation", "", "")
+	flag.StringVar(&codecFlag, "codec", "", "")
+	fpsFlag := flag.String("fps", "", "")
 	durminFlag = flag.Duration("durmin", 0, "")
 	durmaxFlag = flag.Duration("durmax", 1_000_000*time.Hour, "")
 	yearFlag := flag.String("year", "", "")
 	flag.StringVar(&authorFlag, "author", "", "")
+	flag.StringVar(&albumFlag, "album", "", "")
+	flag.StringVar(&titleFlag, "title", "", "")
+	flag.StringVar(&genreFlag, "genre", "", "")
 	flag.BoolVar(&txtFlag, "txt", false, "")
+	flag.StringVar(&textFlag, "text", "", "")
+	flag.StringVar(&categoryFlag, "category", "", "")
+	flag.BoolVar(&similarFlag, "similar", false, "")
+	flag.IntVar(&thresholdFlag, "threshold", 10, "")
+	flag.BoolVar(&explainFlag, "explain", false, "")
+	flag.Var(&tagFlags, "tag", "")
+	flag.BoolVar(&inArchiveFlag, "inarchive", false, "")
+	flag.StringVar(&archFlag, "arch", "", "")
+	flag.StringVar(&interpFlag, "interp", "", "")
 	flag.Parse()
+	if dbFlag != "" && profileFlag != "" ||
+		profileFlag != "" && (strings.ContainsAny(profileFlag, `/\`) || strings.HasPrefix(profileFlag, ".")) {
+		flag.Usage()
+	}
 	if *cFlag != "" {
-		s := strings.Split(*cFlag, "-")
-		switch len(s) {
-		case 1:
-			a, err := strconv.Atoi(s[0])
-			if err != nil {
-				flag.Usage()
-			}
-			createdFromYear, createdUntilYear = &a, &a
-		case 2:
-			a, err := strconv.Atoi(s[0])
-			if err != nil {
-				flag.Usage()
-			}
-			b, err := strconv.Atoi(s[1])
-			if err != nil {
-				flag.Usage()
-			}
-			createdFromYear, createdUntilYear = &a, &b
-		default:
+		var err error
+		if createdSince, createdUntil, err = units.ParseDateRange(*cFlag); err != nil {
 			flag.Usage()
 		}
 	}
+	if *mFlag != "" {
+		var err error
+		if modifiedSince, modifiedUntil, err = units.ParseDateRange(*mFlag); err != nil {
+			flag.Usage()
+		}
+	}
+	if *takenFlag != "" {
+		var err error
+		if takenSince, takenUntil, err = units.ParseDateRange(*takenFlag); err != nil {
+			flag.Usage()
+		}
+	}
+	if *nearFlagValue != "" {
+		var err error
+		if nearFlag, err = parseArea(*nearFlagValue); err != nil {
+			flag.Usage()
+		}
+	}
+	if *minresFlagValue != "" {
+		r, err := units.ParseResolution(*minresFlagValue)
+		if err != nil {
+			flag.Usage()
+		}
+		minresFlag = &r
+	}
+	if *minsizeFlagValue != "" {
+		size, err := units.ParseSize(*minsizeFlagValue)
+		if err != nil {
+			flag.Usage()
+		}
+		minsizeFlag = &size
+	}
+	if *maxsizeFlagValue != "" {
+		size, err := units.ParseSize(*maxsizeFlagValue)
+		if err != nil {
+			flag.Usage()
+		}
+		maxsizeFlag = &size
+	}
+	if re, ok := database.RegexpPattern(nameFlag); ok {
+		if _, err := regexp.Compile(re); err != nil {
+			log.Fatalf("Invalid regular expression '%s': %s\n", re, err)
+		}
+	}
+	switch sortFlag {
+	case "", "modified", "created", "size", "path", "name", "duration", "taken":
+	default:
+		flag.Usage()
+	}
+	if limitFlag < 0 || randomFlag < 0 || randomFlag > 0 && (sortFlag != "" || limitFlag > 0) {
+		flag.Usage()
+	}
+	switch orientationFlag {
+	case "", "portrait", "landscape", "square":
+	default:
+		flag.Usage()
+	}
+	if *fpsFlag != "" {
+		from, until, _ := strings.Cut(*fpsFlag, "-")
+		if until == "" {
+			until = from
+		}
+		low, _, err := parseFrameRate(from)
+		if err != nil {
+			flag.Usage()
+		}
+		_, high, err := parseFrameRate(until)
+		if err != nil {
+			flag.Usage()
+		}
+		minFPS, maxFPS = &low, &high
+	}
+	if thresholdFlag < 0 || thresholdFlag > 64 {
+		flag.Usage()
+	}
+	if jobsFlag < 1 {
+		flag.Usage()
+	}
+	parseFormat(*formatFlag)
 	if *durminFlag == 0 {
 		durminFlag = nil
 	}
@@ -172,6 +555,49 @@ Filters:
 	}
 }
 
+// parseArea parses an area given as "LAT,LON,RADIUS", where RADIUS is
+// given in meters or kilometers, e.g. "500m" or "2km".
+func parseArea(s string) (*database.Area, error) {
+	fields := strings.Split(s, ",")
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("invalid area '%s'", s)
+	}
+	lat, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return nil, fmt.Errorf("invalid latitude '%s'", fields[0])
+	}
+	lon, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil || lon < -180 || lon > 180 {
+		return nil, fmt.Errorf("invalid longitude '%s'", fields[1])
+	}
+	radius, unit := fields[2], 1.0
+	if r, ok := strings.CutSuffix(radius, "km"); ok {
+		radius, unit = r, 1000
+	} else {
+		radius = strings.TrimSuffix(radius, "m")
+	}
+	r, err := strconv.ParseFloat(radius, 64)
+	if err != nil || r < 0 {
+		return nil, fmt.Errorf("invalid radius '%s'", fields[2])
+	}
+	return &database.Area{Latitude: lat, Longitude: lon, Radius: r * unit}, nil
+}
+
+// parseFrameRate parses a frame rate like "30" or "29.97" and returns
+// the range of the frame rates, that round to it with its precision.
+func parseFrameRate(s string) (float64, float64, error) {
+	fps, err := strconv.ParseFloat(s, 64)
+	if err != nil || !(fps > 0) || math.IsInf(fps, 0) {
+		return 0, 0, fmt.Errorf("invalid frame rate '%s'", s)
+	}
+	decimals := 0
+	if _, fraction, ok := strings.Cut(s, "."); ok {
+		decimals = len(fraction)
+	}
+	tolerance := 0.5 / math.Pow10(decimals)
+	return fps - tolerance, fps + tolerance, nil
+}
+
 func main() {
 	switch flag.Arg(0) {
 	case "t", "track":
@@ -182,6 +608,8 @@ func main() {
 		delete()
 	case "r", "rescan":
 		rescan()
+	case "w", "watch":
+		watch()
 	case "p", "pic", "picture":
 		listPictures()
 	case "v", "vid", "video":
@@ -190,10 +618,26 @@ func main() {
 		listAudio()
 	case "d", "doc", "document":
 		listDocuments()
+	case "ar", "archive":
+		listArchives()
+	case "x", "exe", "executable":
+		listExecutables()
 	case "o", "other":
 		listOther()
 	case "all":
 		listAll()
+	case "query":
+		listQuery()
+	case "dupes":
+		listDuplicates()
+	case "similar":
+		listSimilar()
+	case "tag":
+		tag()
+	case "ignore":
+		ignorePatterns()
+	case "db":
+		dbCommand()
 	default:
 		flag.Usage()
 	}
@@ -204,6 +648,12 @@ func add() {
 		log.Fatalln("Give exactly one argument to the add command.")
 	}
 	path := flag.Arg(1)
+	opts := database.TrackOptions{
+		Hidden:         hiddenFlag,
+		FollowSymlinks: followFlag,
+		SameFilesystem: xdevFlag,
+		IgnorePatterns: ignoreFlags,
+	}
 	progress := make(chan den.Progress)
 	var wg sync.WaitGroup
 	wg.Go(func() {
@@ -220,7 +670,7 @@ func add() {
 			}
 		}
 	})
-	if err := den.Add(path, db, progress); err != nil {
+	if err := den.Add(path, opts, db, jobsFlag, progress); err != nil {
 		log.Fatalln("Could not index dir:", err)
 	}
 	wg.Wait()
@@ -237,7 +687,31 @@ func listTracked() {
 	}
 	slices.Sort(paths)
 	for _, p := range paths {
-		fmt.Println(p)
+		if !lFlag {
+			fmt.Println(p)
+			continue
+		}
+		opts, err := db.TrackOptions(p)
+		if err != nil {
+			log.Fatalln("Could not query options of tracked path:", err)
+		}
+		var options []string
+		if opts.Hidden {
+			options = append(options, "hidden")
+		}
+		if opts.FollowSymlinks {
+			options = append(options, "follow-symlinks")
+		}
+		if opts.SameFilesystem {
+			options = append(options, "xdev")
+		}
+		if len(opts.IgnorePatterns) > 0 {
+			options = append(options, fmt.Sprintf("ignore=%d", len(opts.IgnorePatterns)))
+		}
+		if len(options) == 0 {
+			options = append(options, "-")
+		}
+		fmt.Printf("%s\t%s\n", p, strings.Join(options, ","))
 	}
 }
 
@@ -252,9 +726,6 @@ func delete() {
 }
 
 func rescan() {
-	if flag.NArg() != 1 {
-		log.Fatalln("Got unexpected arguments for the rescan command.")
-	}
 	checkProgress := make(chan den.Progress)
 	indexProgress := make(chan den.Progress)
 	var wg sync.WaitGroup
@@ -292,9 +763,167 @@ func rescan() {
 			}
 		}
 	})
-	if err := den.Rescan(db, checkProgress, indexProgress); err != nil {
+	if err := den.Rescan(db, absPaths(flag.Args()[1:]), jobsFlag, checkProgress, indexProgress); err != nil {
 		log.Fatalf("Could not rescan: %s\n", err)
 	}
 	wg.Wait()
 	fmt.Fprintf(os.Stderr, "done\n")
 }
+
+func watch() {
+	if flag.NArg() != 1 {
+		log.Fatalln("Got unexpected arguments for the watch command.")
+	}
+	updates := make(chan den.WatchUpdate)
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		for update := range updates {
+			if update.Rescanned {
+				fmt.Fprintln(os.Stderr, "Changes were lost; rescanned all tracked paths.")
+			} else {
+				fmt.Fprintf(os.Stderr, "Indexed %d and removed %d files.\n",
+					update.Indexed, update.Removed)
+			}
+		}
+	})
+	err := den.Watch(db, updates)
+	wg.Wait()
+	log.Fatalln("Could not watch tracked paths:", err)
+}
+
+func tag() {
+	switch {
+	case flag.NArg() >= 4 && flag.Arg(1) == "add":
+		name := flag.Arg(2)
+		if name == "" || strings.HasPrefix(name, "!") {
+			log.Fatalf("Invalid tag '%s'.\n", name)
+		}
+		if err := db.TagFiles(name, absPaths(flag.Args()[3:])); err != nil {
+			log.Fatalln("Could not add tag:", err)
+		}
+	case flag.NArg() >= 4 && flag.Arg(1) == "rm":
+		if err := db.UntagFiles(flag.Arg(2), absPaths(flag.Args()[3:])); err != nil {
+			log.Fatalln("Could not remove tag:", err)
+		}
+	case flag.NArg() >= 2 && flag.Arg(1) == "list":
+		tags, err := db.Tags(absPaths(flag.Args()[2:]))
+		if err != nil {
+			log.Fatalln("Could not list tags:", err)
+		}
+		for _, t := range tags {
+			fmt.Printf("%s\t%d\n", t.Value, t.Count)
+		}
+	default:
+		flag.Usage()
+	}
+}
+
+func ignorePatterns() {
+	switch {
+	case flag.NArg() >= 4 && flag.Arg(1) == "add":
+		patterns := flag.Args()[3:]
+		for _, p := range patterns {
+			if err := ignore.Validate(p); err != nil {
+				log.Fatalln("Could not add ignore patterns:", err)
+			}
+		}
+		if err := db.AddIgnorePatterns(absPaths(flag.Args()[2:3])[0], patterns); err != nil {
+			log.Fatalln("Could not add ignore patterns:", err)
+		}
+	case flag.NArg() >= 4 && flag.Arg(1) == "rm":
+		if err := db.RemoveIgnorePatterns(absPaths(flag.Args()[2:3])[0], flag.Args()[3:]); err != nil {
+			log.Fatalln("Could not remove ignore patterns:", err)
+		}
+	case flag.NArg() <= 3 && flag.Arg(1) == "list":
+		paths := absPaths(flag.Args()[2:])
+		if len(paths) == 0 {
+			var err error
+			if paths, err = den.List(db); err != nil {
+				log.Fatalln("Could not list tracked paths:", err)
+			}
+			slices.Sort(paths)
+		}
+		for _, path := range paths {
+			patterns, err := db.IgnorePatterns(path)
+			if err != nil {
+				log.Fatalln("Could not list ignore patterns:", err)
+			}
+			for _, p := range patterns {
+				fmt.Printf("%s\t%s\n", path, p)
+			}
+		}
+	default:
+		flag.Usage()
+	}
+}
+
+func dbCommand() {
+	switch {
+	case flag.NArg() == 2 && flag.Arg(1) == "migrate":
+		pending, err := db.PendingMigrations()
+		if err != nil {
+			log.Fatalln("Could not update database schema:", err)
+		}
+		migrate()
+		for _, m := range pending {
+			fmt.Printf("Updated schema to version %d: %s.\n", m.Version, m.Description)
+		}
+		if len(pending) == 0 {
+			fmt.Printf("The schema is up to date at version %d.\n", database.SchemaVersion)
+		}
+	case flag.NArg() == 3 && flag.Arg(1) == "migrate" && flag.Arg(2) == "-n":
+		pending, err := db.PendingMigrations()
+		if err != nil {
+			log.Fatalln("Could not check database schema:", err)
+		}
+		for _, m := range pending {
+			fmt.Printf("Would update schema to version %d: %s.\n", m.Version, m.Description)
+		}
+		if len(pending) == 0 {
+			fmt.Printf("The schema is up to date at version %d.\n", database.SchemaVersion)
+		}
+	default:
+		flag.Usage()
+	}
+}
+
+func absPaths(paths []string) []string {
+	abs := make([]string, len(paths))
+	for i, path := range paths {
+		var err error
+		if abs[i], err = filepath.Abs(path); err != nil {
+			log.Fatalf("Could not normalize path '%s': %s\n", path, err)
+		}
+	}
+	return abs
+}
+
+// tagList collects the values of the repeatable -tag flag.
+type tagList []string
+
+func (t *tagList) String() string {
+	return strings.Join(*t, ",")
+}
+
+func (t *tagList) Set(tag string) error {
+	if tag == "" || tag == "!" {
+		return fmt.Errorf("empty tag")
+	}
+	*t = append(*t, tag)
+	return nil
+}
+
+// patternList collects the values of the repeatable -ignore flag.
+type patternList []string
+
+func (p *patternList) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *patternList) Set(pattern string) error {
+	if err := ignore.Validate(pattern); err != nil {
+		return err
+	}
+	*p = append(*p, pattern)
+	return nil
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/codesoap/den/database"
+)
+
+type format int
+
+const (
+	formatPaths format = iota
+	formatNull
+	formatJSON
+	formatNDJSON
+	formatCSV
+	formatTemplate
+)
+
+// record holds the values of a listed file. It is also the value a
+// template given with -format is executed with. Values that are unknown
+// or do not apply to the listed category are nil.
+type record struct {
+	Path         string
+	Size         int64
+	Modified     time.Time
+	CreatedGuess time.Time
+	MIME         string
+	Taken        *time.Time
+	Camera       *string
+	Duration     *time.Duration
+	Author       *string
+	Year         *int
+	Latitude     *float64
+	Longitude    *float64
+	Altitude     *float64
+	Width        *int
+	Height       *int
+	FrameRate    *float64
+	VideoCodec   *string
+	AudioCodec   *string
+	Bitrate      *int
+	AlbumArtist  *string
+	Album        *string
+	Title        *string
+	Genre        *string
+	Track        *int
+	Disc         *int
+	Arch         *string
+	Linking      *string
+	Interpreter  *string
+
+	// Snippet is only set for documents found by a full-text search.
+	Snippet *string
+}
+
+// The columns of the structured output formats for each category.
+var (
+	fileColumns    = []string{"path", "size", "modified", "created_guess", "mime", "taken"}
+	pictureColumns = slices.Concat(fileColumns, []string{"camera",
+		"latitude", "longitude", "altitude", "width", "height"})
+	videoColumns = slices.Concat(fileColumns, []string{"duration", "camera", "year",
+		"latitude", "longitude", "altitude", "width", "height", "fps",
+		"video_codec", "audio_codec", "bitrate"})
+	audioColumns = slices.Concat(fileColumns, []string{"duration", "author", "year",
+		"album_artist", "album", "title", "genre", "track", "disc", "audio_codec", "bitrate"})
+	documentColumns   = fileColumns
+	archiveColumns    = fileColumns
+	executableColumns = slices.Concat(fileColumns, []string{"arch", "linking", "interpreter"})
+	allColumns        = slices.Concat(fileColumns,
+		[]string{"camera", "duration", "author", "year", "latitude", "longitude",
+			"altitude", "width", "height", "fps", "video_codec", "audio_codec", "bitrate",
+			"album_artist", "album", "title", "genre", "track", "disc",
+			"arch", "linking", "interpreter"})
+)
+
+// printer prints records in the format selected with -format.
+type printer struct {
+	w         *bufio.Writer
+	csv       *csv.Writer
+	columns   []string
+	formatted int
+}
+
+func newPrinter(columns []string) *printer {
+	p := &printer{
+		w:       bufio.NewWriter(os.Stdout),
+		columns: columns,
+	}
+	switch outputFormat {
+	case formatJSON:
+		p.w.WriteString("[")
+	case formatCSV:
+		p.csv = csv.NewWriter(p.w)
+		p.csv.Write(columns)
+	}
+	return p
+}
+
+func (p *printer) print(r record) error {
+	defer func() { p.formatted++ }()
+	switch outputFormat {
+	case formatPaths:
+		p.w.WriteString(r.Path + "\n")
+		if r.Snippet != nil {
+			p.w.WriteString("\t" + strings.Join(strings.Fields(*r.Snippet), " ") + "\n")
+		}
+	case formatNull:
+		p.w.WriteString(r.Path + "\x00")
+	case formatJSON:
+		if p.formatted > 0 {
+			p.w.WriteString(",")
+		}
+		p.w.WriteString("\n  ")
+		return p.writeJSONObject(r)
+	case formatNDJSON:
+		if err := p.writeJSONObject(r); err != nil {
+			return err
+		}
+		p.w.WriteString("\n")
+	case formatCSV:
+		values := make([]string, len(p.columns))
+		for i, column := range p.columns {
+			values[i] = csvValue(r.value(column))
+		}
+		return p.csv.Write(values)
+	case formatTemplate:
+		if err := outputTemplate.Execute(p.w, r); err != nil {
+			return fmt.Errorf("could not execute template: %s", err)
+		}
+		p.w.WriteString("\n")
+	}
+	return nil
+}
+
+// close finishes the output and flushes it.
+func (p *printer) close() error {
+	switch outputFormat {
+	case formatJSON:
+		p.w.WriteString("\n]\n")
+	case formatCSV:
+		p.csv.Flush()
+		if err := p.csv.Error(); err != nil {
+			return err
+		}
+	}
+	return p.w.Flush()
+}
+
+// writeJSONObject writes the columns of r as a JSON object. The keys
+// are written in the order of the columns.
+func (p *printer) writeJSONObject(r record) error {
+	p.w.WriteString("{")
+	for i, column := range p.columns {
+		if i > 0 {
+			p.w.WriteString(",")
+		}
+		value, err := json.Marshal(r.value(column))
+		if err != nil {
+			return err
+		}
+		p.w.WriteString(strconv.Quote(column) + ":")
+		p.w.Write(value)
+	}
+	p.w.WriteString("}")
+	return nil
+}
+
+// value returns the value of the given column in the form used by the
+// JSON and CSV formats. Times are formatted according to RFC 3339 and
+// durations are given in seconds.
+func (r record) value(column string) any {
+	switch column {
+	case "path":
+		return r.Path
+	case "size":
+		return r.Size
+	case "modified":
+		return r.Modified.Format(time.RFC3339)
+	case "created_guess":
+		return r.CreatedGuess.Format(time.RFC3339)
+	case "mime":
+		return r.MIME
+	case "taken":
+		if r.Taken == nil {
+			return nil
+		}
+		return r.Taken.Format(time.RFC3339)
+	case "camera":
+		return r.Camera
+	case "duration":
+		if r.Duration == nil {
+			return nil
+		}
+		return int64(r.Duration.Seconds())
+	case "author":
+		return r.Author
+	case "year":
+		return r.Year
+	case "latitude":
+		return r.Latitude
+	case "longitude":
+		return r.Longitude
+	case "altitude":
+		return r.Altitude
+	case "width":
+		return r.Width
+	case "height":
+		return r.Height
+	case "fps":
+		return r.FrameRate
+	case "video_codec":
+		return r.VideoCodec
+	case "audio_codec":
+		return r.AudioCodec
+	case "bitrate":
+		return r.Bitrate
+	case "album_artist":
+		return r.AlbumArtist
+	case "album":
+		return r.Album
+	case "title":
+		return r.Title
+	case "genre":
+		return r.Genre
+	case "track":
+		return r.Track
+	case "disc":
+		return r.Disc
+	case "arch":
+		return r.Arch
+	case "linking":
+		return r.Linking
+	case "interpreter":
+		return r.Interpreter
+	case "snippet":
+		return r.Snippet
+	}
+	panic(fmt.Sprintf("unknown column '%s'", column))
+}
+
+func csvValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case *string:
+		if v != nil {
+			return *v
+		}
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case *int:
+		if v != nil {
+			return strconv.Itoa(*v)
+		}
+	case *float64:
+		if v != nil {
+			return strconv.FormatFloat(*v, 'f', -1, 64)
+		}
+	}
+	return ""
+}
+
+// toRecord converts an entry of any category to a record.
+func toRecord(e database.Entry) record {
+	f := e.Base()
+	r := record{
+		Path:         f.Path,
+		Size:         f.Size,
+		Modified:     f.Modified,
+		CreatedGuess: f.CreatedGuess,
+		MIME:         f.MIME,
+		Taken:        f.Taken,
+	}
+	switch e := e.(type) {
+	case *database.Picture:
+		r.Camera = optional(e.Camera)
+		r.setLocation(e.Location)
+		r.Width, r.Height = e.Width, e.Height
+	case *database.Video:
+		r.Duration = e.Duration
+		r.Camera = optional(e.Camera)
+		r.Year = e.Year
+		r.setLocation(e.Location)
+		r.Width, r.Height = e.Width, e.Height
+		r.FrameRate = e.FrameRate
+		r.VideoCodec = optional(e.VideoCodec)
+		r.AudioCodec = optional(e.AudioCodec)
+		r.Bitrate = e.Bitrate
+	case *database.Audio:
+		r.Duration = e.Duration
+		r.Author = optional(e.Author)
+		r.Year = e.Year
+		r.AlbumArtist = optional(e.AlbumArtist)
+		r.Album = optional(e.Album)
+		r.Title = optional(e.Title)
+		r.Genre = optional(e.Genre)
+		r.Track = e.Track
+		r.Disc = e.Disc
+		r.AudioCodec = optional(e.Codec)
+		r.Bitrate = e.Bitrate
+	case *database.Executable:
+		r.Arch = optional(e.Arch)
+		r.Linking = optional(e.Linking)
+		r.Interpreter = optional(e.Interpreter)
+	case *database.Document:
+		r.Snippet = optional(e.Snippet)
+	}
+	return r
+}
+
+func (r *record) setLocation(l *database.Location) {
+	if l != nil {
+		r.Latitude, r.Longitude, r.Altitude = &l.Latitude, &l.Longitude, l.Altitude
+	}
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func parseFormat(f string) {
+	switch f {
+	case "paths":
+		outputFormat = formatPaths
+	case "null":
+		outputFormat = formatNull
+	case "json":
+		outputFormat = formatJSON
+	case "ndjson":
+		outputFormat = formatNDJSON
+	case "csv":
+		outputFormat = formatCSV
+	default:
+		if !strings.Contains(f, "{{") {
+			log.Fatalf("Unknown format '%s'.\n", f)
+		}
+		var err error
+		outputTemplate, err = template.New("format").Parse(f)
+		if err != nil {
+			log.Fatalln("Could not parse format template:", err)
+		}
+		outputFormat = formatTemplate
+	}
+}
@@ -1,12 +1,19 @@
 package main
 
 import (
+	"errors"
 	"flag"
+	"fmt"
+	"iter"
 	"log"
+	"os"
 	"path/filepath"
-	"time"
+	"slices"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/codesoap/den/database"
+	"github.com/codesoap/den/internal/querylang"
 )
 
 func listPictures() {
@@ -14,16 +21,23 @@ func listPictures() {
 		log.Fatalln("Too many arguments.")
 	}
 	if dFlag {
-		if err := db.PrintPicturesDetails(); err != nil {
+		details, err := db.PicturesDetails()
+		if err != nil {
 			log.Fatalf("Could not query file statistics: %s\n", err)
 		}
+		printPicturesDetails(details)
 	} else {
 		f := database.PictureFilter{
 			FileFilter: createFilter(),
 			Camera:     cameraFlag,
+			Geotagged:  geoFlag,
+			Near:       nearFlag,
+
+			MinResolution: minresFlag,
+			Orientation:   orientationFlag,
 		}
-		if err := db.PrintPicturesPaths(f); err != nil {
-			log.Fatalf("Could not query files: %s\n", err)
+		if printEntries(db.Pictures(f), pictureColumns) == 0 {
+			suggestPictureValues()
 		}
 	}
 }
@@ -33,9 +47,11 @@ func listVideos() {
 		log.Fatalln("Too many arguments.")
 	}
 	if dFlag {
-		if err := db.PrintVideosDetails(); err != nil {
+		details, err := db.VideosDetails()
+		if err != nil {
 			log.Fatalf("Could not query file statistics: %s\n", err)
 		}
+		printVideosDetails(details)
 	} else {
 		f := database.VideoFilter{
 			FileFilter:  createFilter(),
@@ -44,9 +60,17 @@ func listVideos() {
 			Camera:      cameraFlag,
 			MinYear:     recordedFromYear,
 			MaxYear:     recordedUntilYear,
+			Geotagged:   geoFlag,
+			Near:        nearFlag,
+
+			MinResolution: minresFlag,
+			Orientation:   orientationFlag,
+			Codec:         codecFlag,
+			MinFrameRate:  minFPS,
+			MaxFrameRate:  maxFPS,
 		}
-		if err := db.PrintVideosPaths(f); err != nil {
-			log.Fatalf("Could not query files: %s\n", err)
+		if printEntries(db.Videos(f), videoColumns) == 0 {
+			suggestVideoValues()
 		}
 	}
 }
@@ -56,9 +80,11 @@ func listAudio() {
 		log.Fatalln("Too many arguments.")
 	}
 	if dFlag {
-		if err := db.PrintAudiosDetails(); err != nil {
+		details, err := db.AudiosDetails()
+		if err != nil {
 			log.Fatalf("Could not query file statistics: %s\n", err)
 		}
+		printAudiosDetails(details)
 	} else {
 		f := database.AudioFilter{
 			FileFilter:  createFilter(),
@@ -67,9 +93,13 @@ func listAudio() {
 			Author:      authorFlag,
 			MinYear:     recordedFromYear,
 			MaxYear:     recordedUntilYear,
+			Album:       albumFlag,
+			Title:       titleFlag,
+			Genre:       genreFlag,
+			Codec:       codecFlag,
 		}
-		if err := db.PrintAudiosPaths(f); err != nil {
-			log.Fatalf("Could not query files: %s\n", err)
+		if printEntries(db.Audios(f), audioColumns) == 0 {
+			suggestAudioValues()
 		}
 	}
 }
@@ -79,61 +109,248 @@ func listDocuments() {
 		log.Fatalln("Too many arguments.")
 	}
 	if dFlag {
-		if err := db.PrintDocumentsDetails(); err != nil {
+		details, err := db.DocumentsDetails()
+		if err != nil {
 			log.Fatalf("Could not query file statistics: %s\n", err)
 		}
+		printCreatedYears("Documents can be filtered by the year of creation:",
+			"\t`-c %d` lists all documents created in %d (%d files).\n", details)
+		printSizes("Documents can be filtered by their size:",
+			"\t`%s` lists all documents in that size range (%d files).\n", details)
+		printTags("Documents can be filtered by tag:",
+			"\t`-tag '%s'` lists all documents with that tag (%d files).\n", details)
 	} else {
 		f := database.DocumentFilter{
 			FileFilter: createFilter(),
 			TxtOnly:    txtFlag,
+			Text:       textFlag,
 		}
-		if err := db.PrintDocumentsPaths(f); err != nil {
-			log.Fatalf("Could not query files: %s\n", err)
+		columns := documentColumns
+		if textFlag != "" {
+			columns = slices.Concat(columns, []string{"snippet"})
 		}
+		printEntries(db.Documents(f), columns)
 	}
 }
 
-func listOther() {
+func listArchives() {
 	if flag.NArg() > 2 {
 		log.Fatalln("Too many arguments.")
 	}
 	if dFlag {
-		if err := db.PrintOthersDetails(); err != nil {
+		details, err := db.ArchivesDetails()
+		if err != nil {
 			log.Fatalf("Could not query file statistics: %s\n", err)
 		}
+		printCreatedYears("Archives can be filtered by the year of creation:",
+			"\t`-c %d` lists all archives created in %d (%d archives).\n", details)
+		printSizes("Archives can be filtered by their size:",
+			"\t`%s` lists all archives in that size range (%d archives).\n", details)
+		printTags("Archives can be filtered by tag:",
+			"\t`-tag '%s'` lists all archives with that tag (%d archives).\n", details)
 	} else {
-		if err := db.PrintOthersPaths(createFilter()); err != nil {
-			log.Fatalf("Could not query files: %s\n", err)
+		printEntries(db.Archives(createFilter()), archiveColumns)
+	}
+}
+
+func listExecutables() {
+	if flag.NArg() > 2 {
+		log.Fatalln("Too many arguments.")
+	}
+	if dFlag {
+		details, err := db.ExecutablesDetails()
+		if err != nil {
+			log.Fatalf("Could not query file statistics: %s\n", err)
+		}
+		printExecutablesDetails(details)
+	} else {
+		f := database.ExecutableFilter{
+			FileFilter:  createFilter(),
+			Arch:        archFlag,
+			Interpreter: interpFlag,
+		}
+		if printEntries(db.Executables(f), executableColumns) == 0 {
+			suggestExecutableValues()
 		}
 	}
 }
 
+func listOther() {
+	if flag.NArg() > 2 {
+		log.Fatalln("Too many arguments.")
+	}
+	if dFlag {
+		details, err := db.OthersDetails()
+		if err != nil {
+			log.Fatalf("Could not query file statistics: %s\n", err)
+		}
+		printCreatedYears("Other files can be filtered by the year of creation:",
+			"\t`-c %d` lists all files created in %d (%d files).\n", details)
+		printSizes("Other files can be filtered by their size:",
+			"\t`%s` lists all files in that size range (%d files).\n", details)
+		printTags("Other files can be filtered by tag:",
+			"\t`-tag '%s'` lists all files with that tag (%d files).\n", details)
+	} else {
+		printEntries(db.Others(createFilter()), fileColumns)
+	}
+}
+
 func listAll() {
 	if flag.NArg() > 2 {
 		log.Fatalln("Too many arguments.")
 	}
 	if dFlag {
-		if err := db.PrintAllDetails(); err != nil {
+		details, err := db.AllDetails()
+		if err != nil {
 			log.Fatalf("Could not query file statistics: %s\n", err)
 		}
+		printCreatedYears("All files can be filtered by the year of creation:",
+			"\t`-c %d` lists all files created in %d (%d files).\n", details)
+		printSizes("All files can be filtered by their size:",
+			"\t`%s` lists all files in that size range (%d files).\n", details)
+		printTags("All files can be filtered by tag:",
+			"\t`-tag '%s'` lists all files with that tag (%d files).\n", details)
 	} else {
-		if err := db.PrintAllPaths(createFilter()); err != nil {
+		printEntries(db.All(createFilter()), allColumns)
+	}
+}
+
+func listQuery() {
+	if flag.NArg() < 2 {
+		log.Fatalln("Give an expression to the query command.")
+	}
+	expression := strings.Join(flag.Args()[1:], " ")
+	e, err := querylang.Parse(expression)
+	if err != nil {
+		fatalExpressionError(expression, err)
+	}
+	f := createFilter()
+	f.Prefix = "" // The arguments are the expression.
+	q, args, err := database.QuerySQL(e, f)
+	if err != nil {
+		fatalExpressionError(expression, err)
+	}
+	if explainFlag {
+		fmt.Println(q)
+		for i, arg := range args {
+			fmt.Printf("?%d = %#v\n", i+1, arg)
+		}
+		return
+	}
+	printEntries(db.Query(e, f), allColumns)
+}
+
+// fatalExpressionError prints err and, if it refers to a position
+// within expression, marks that position.
+func fatalExpressionError(expression string, err error) {
+	var exprErr *querylang.Error
+	if !errors.As(err, &exprErr) {
+		log.Fatalln("Invalid query:", err)
+	}
+	log.Printf("Invalid query: %s\n", exprErr.Msg)
+	log.Printf("    %s\n", expression)
+	log.Fatalf("    %s^\n", strings.Repeat(" ", utf8.RuneCountInString(expression[:exprErr.Pos])))
+}
+
+func listDuplicates() {
+	if flag.NArg() > 2 {
+		log.Fatalln("Too many arguments.")
+	}
+	f := database.DuplicateFilter{
+		FileFilter: createFilter(),
+		Category:   categoryFlag,
+		Similar:    similarFlag,
+		Threshold:  thresholdFlag,
+	}
+	var groups, files int
+	var reclaimable int64
+	for group, err := range db.Duplicates(f) {
+		if err != nil {
+			log.Fatalf("Could not query duplicates: %s\n", err)
+		}
+		if groups > 0 {
+			fmt.Println()
+		}
+		for i, file := range group {
+			fmt.Println(file.Path)
+			if i > 0 {
+				reclaimable += file.Size
+			}
+		}
+		groups++
+		files += len(group)
+	}
+	if groups > 0 {
+		fmt.Fprintf(os.Stderr, "\nFound %d files in %d groups of duplicates; %s could be reclaimed.\n",
+			files, groups, formatSize(reclaimable))
+	}
+}
+
+func listSimilar() {
+	if flag.NArg() != 2 {
+		log.Fatalln("Give exactly one picture to the similar command.")
+	}
+	path, err := filepath.Abs(flag.Arg(1))
+	if err != nil {
+		log.Fatalf("Could not find similar pictures: %s\n", err)
+	}
+	printEntries(db.SimilarPictures(path, thresholdFlag), pictureColumns)
+}
+
+// formatSize formats a number of bytes in a human readable way.
+func formatSize(bytes int64) string {
+	const units = "KMGTPE"
+	if bytes < 1024 {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	size, i := float64(bytes)/1024, 0
+	for size >= 1024 && i < len(units)-1 {
+		size /= 1024
+		i++
+	}
+	return fmt.Sprintf("%.1f %ciB", size, units[i])
+}
+
+// printEntries prints the entries in the format selected with -format
+// and returns their number.
+func printEntries[T database.Entry](entries iter.Seq2[T, error], columns []string) int {
+	p := newPrinter(columns)
+	for entry, err := range entries {
+		if err != nil {
 			log.Fatalf("Could not query files: %s\n", err)
 		}
+		if err = p.print(toRecord(entry)); err != nil {
+			log.Fatalf("Could not print files: %s\n", err)
+		}
 	}
+	if err := p.close(); err != nil {
+		log.Fatalf("Could not print files: %s\n", err)
+	}
+	return p.formatted
 }
 
 func createFilter() database.FileFilter {
-	f := database.FileFilter{}
-	if createdFromYear != nil {
-		since := time.Date(*createdFromYear, time.January, 1, 0, 0, 0, 0, time.Local)
-		f.CreatedSince = &since
-	}
-	if createdUntilYear != nil {
-		until := time.
-			Date(*createdUntilYear+1, time.January, 1, 0, 0, 0, 0, time.Local).
-			Add(-time.Nanosecond)
-		f.CreatedUntil = &until
+	f := database.FileFilter{
+		Name:      nameFlag,
+		InArchive: inArchiveFlag,
+		MinSize:   minsizeFlag,
+		MaxSize:   maxsizeFlag,
+		Sort:      sortFlag,
+		Reverse:   reverseFlag,
+		Limit:     limitFlag,
+	}
+	if randomFlag > 0 {
+		f.Random, f.Limit = true, randomFlag
+	}
+	f.CreatedSince, f.CreatedUntil = createdSince, createdUntil
+	f.ModifiedSince, f.ModifiedUntil = modifiedSince, modifiedUntil
+	f.TakenSince, f.TakenUntil = takenSince, takenUntil
+	for _, tag := range tagFlags {
+		if excluded, ok := strings.CutPrefix(tag, "!"); ok {
+			f.ExcludedTags = append(f.ExcludedTags, excluded)
+		} else {
+			f.Tags = append(f.Tags, tag)
+		}
 	}
 	if flag.NArg() == 2 {
 		var err error
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/codesoap/den/database"
+)
+
+// maxSuggestions is the maximum number of values suggested for a text
+// filter, that matched no value.
+const maxSuggestions = 3
+
+func suggestPictureValues() {
+	if cameraFlag == "" {
+		return
+	}
+	details, err := db.PicturesDetails()
+	if err != nil {
+		log.Fatalf("Could not query file statistics: %s\n", err)
+	}
+	suggest("camera", cameraFlag, details.Cameras)
+}
+
+func suggestVideoValues() {
+	if cameraFlag == "" && codecFlag == "" {
+		return
+	}
+	details, err := db.VideosDetails()
+	if err != nil {
+		log.Fatalf("Could not query file statistics: %s\n", err)
+	}
+	suggest("camera", cameraFlag, details.Cameras)
+	suggest("codec", codecFlag, slices.Concat(details.VideoCodecs, details.AudioCodecs))
+}
+
+func suggestAudioValues() {
+	if authorFlag == "" && albumFlag == "" && genreFlag == "" && codecFlag == "" {
+		return
+	}
+	details, err := db.AudiosDetails()
+	if err != nil {
+		log.Fatalf("Could not query file statistics: %s\n", err)
+	}
+	suggest("author", authorFlag, details.Authors)
+	albums := make([]database.ValueCount, len(details.Albums))
+	for i, a := range details.Albums {
+		albums[i] = database.ValueCount{Value: a.Album, Count: a.Count}
+	}
+	suggest("album", albumFlag, albums)
+	suggest("genre", genreFlag, details.Genres)
+	suggest("codec", codecFlag, details.Codecs)
+}
+
+func suggestExecutableValues() {
+	if archFlag == "" && interpFlag == "" {
+		return
+	}
+	details, err := db.ExecutablesDetails()
+	if err != nil {
+		log.Fatalf("Could not query file statistics: %s\n", err)
+	}
+	suggest("arch", archFlag, details.Archs)
+	suggest("interp", interpFlag, details.Interpreters)
+}
+
+// suggest prints the values closest to the text filter given with
+// -name, if it matches none of values. Regular expressions and glob
+// patterns are not considered.
+func suggest(name, pattern string, values []database.ValueCount) {
+	_, isRegexp := database.RegexpPattern(pattern)
+	if pattern == "" || isRegexp || database.IsGlobPattern(pattern) {
+		return
+	}
+	substring, isSubstring := strings.CutPrefix(pattern, "~")
+	wanted := strings.ToLower(substring)
+	type candidate struct {
+		database.ValueCount
+		distance int
+	}
+	var candidates []candidate
+	for _, v := range values {
+		value := strings.ToLower(v.Value)
+		if value == wanted || (isSubstring && strings.Contains(value, wanted)) {
+			return // The filter matches, so another one excluded all files.
+		}
+		d := levenshtein(wanted, value)
+		if strings.Contains(value, wanted) {
+			d = 0
+		}
+		if d <= max(2, len([]rune(wanted))/2) {
+			candidates = append(candidates, candidate{v, d})
+		}
+	}
+	if len(candidates) == 0 {
+		return
+	}
+	slices.SortStableFunc(candidates, func(a, b candidate) int {
+		if a.distance != b.distance {
+			return a.distance - b.distance
+		}
+		return b.Count - a.Count
+	})
+	fmt.Fprintf(os.Stderr, "No value matches -%s '%s'. Did you mean:\n", name, pattern)
+	for _, c := range candidates[:min(len(candidates), maxSuggestions)] {
+		fmt.Fprintf(os.Stderr, "\t-%s '%s' (%d files)\n", name, c.Value, c.Count)
+	}
+}
+
+// levenshtein returns the number of inserted, deleted or substituted
+// runes needed to turn a into b.
+func levenshtein(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	row := make([]int, len(t)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := range s {
+		prev := row[0]
+		row[0] = i + 1
+		for j := range t {
+			substitution := prev
+			if s[i] != t[j] {
+				substitution++
+			}
+			prev = row[j+1]
+			row[j+1] = min(row[j+1]+1, row[j]+1, substitution)
+		}
+	}
+	return row[len(t)]
+}
@@ -12,9 +12,25 @@ type queryable interface {
 	Query(query string, args ...any) (*sql.Rows, error)
 }
 
-// TrackPath adds a path to be tracked. It will return an error if the
-// path is already tracked.
-func (db *DB) TrackPath(path string) error {
+// TrackOptions control which files within a tracked path are indexed.
+type TrackOptions struct {
+	// Hidden includes hidden files, which are skipped otherwise.
+	Hidden bool
+
+	// FollowSymlinks follows symbolic links to files and directories.
+	FollowSymlinks bool
+
+	// SameFilesystem skips directories on other file systems than the
+	// tracked path itself.
+	SameFilesystem bool
+
+	// IgnorePatterns are gitignore-style patterns of files to ignore.
+	IgnorePatterns []string
+}
+
+// TrackPath adds a path to be tracked with the given options. It will
+// return an error if the path is already tracked.
+func (db *DB) TrackPath(path string, opts TrackOptions) error {
 	path, err := filepath.Abs(path)
 	if err != nil {
 		return fmt.Errorf("could not normalize path: %s", err)
@@ -45,11 +61,19 @@ func (db *DB) TrackPath(path string) error {
 			return fmt.Errorf(f, oldPath)
 		}
 	}
-	_, err = tx.Exec(`INSERT INTO tracked_path (path) VALUES (?)`, path)
+	q := `INSERT INTO tracked_path (path, hidden, follow_symlinks, xdev) VALUES (?, ?, ?, ?)`
+	_, err = tx.Exec(q, path, opts.Hidden, opts.FollowSymlinks, opts.SameFilesystem)
 	if err != nil {
 		tx.Rollback()
 		return fmt.Errorf("could not track path: %s", err)
 	}
+	for _, pattern := range opts.IgnorePatterns {
+		q := `INSERT OR IGNORE INTO ignore_pattern (tracked_path, pattern) VALUES (?, ?)`
+		if _, err = tx.Exec(q, path, pattern); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("could not store ignore pattern: %s", err)
+		}
+	}
 	if err = tx.Commit(); err != nil {
 		return fmt.Errorf("could not commit transaction: %s", err)
 	}
@@ -110,6 +134,10 @@ func (db *DB) UntrackPath(path string) error {
 			return fmt.Errorf("could not delete old entries: %s", err)
 		}
 	}
+	if _, err = tx.Exec(deleteOrphanedTags); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("could not delete tags of removed files: %s", err)
+	}
 
 	if err = tx.Commit(); err != nil {
 		return fmt.Errorf("could not commit transaction: %s", err)
@@ -132,3 +160,86 @@ func trackedPaths(q queryable) ([]string, error) {
 	}
 	return paths, nil
 }
+
+// TrackOptions returns the options of the tracked path.
+func (db *DB) TrackOptions(path string) (TrackOptions, error) {
+	var opts TrackOptions
+	q := `SELECT hidden, follow_symlinks, xdev FROM tracked_path WHERE path = ?`
+	err := db.d.QueryRow(q, path).Scan(&opts.Hidden, &opts.FollowSymlinks, &opts.SameFilesystem)
+	if err == sql.ErrNoRows {
+		return opts, fmt.Errorf("'%s' is not a tracked path", path)
+	} else if err != nil {
+		return opts, fmt.Errorf("could not query options of tracked path: %s", err)
+	}
+	opts.IgnorePatterns, err = db.IgnorePatterns(path)
+	return opts, err
+}
+
+// IgnorePatterns returns the ignore patterns of the tracked path, in
+// the order they were added.
+func (db *DB) IgnorePatterns(path string) ([]string, error) {
+	q := `SELECT pattern FROM ignore_pattern WHERE tracked_path = ? ORDER BY rowid`
+	rows, err := db.d.Query(q, path)
+	if err != nil {
+		return nil, fmt.Errorf("could not query ignore patterns: %s", err)
+	}
+	defer rows.Close()
+	patterns := make([]string, 0)
+	for rows.Next() {
+		var pattern string
+		if err := rows.Scan(&pattern); err != nil {
+			return nil, fmt.Errorf("could not read ignore pattern: %s", err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, rows.Err()
+}
+
+// AddIgnorePatterns adds ignore patterns to the tracked path. Files
+// that become ignored are removed by the next rescan.
+func (db *DB) AddIgnorePatterns(path string, patterns []string) error {
+	tx, err := db.d.Begin()
+	if err != nil {
+		return fmt.Errorf("could not start transaction: %s", err)
+	}
+	var exists bool
+	q := `SELECT EXISTS (SELECT 1 FROM tracked_path WHERE path = ?)`
+	if err = tx.QueryRow(q, path).Scan(&exists); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("could not query tracked paths: %s", err)
+	} else if !exists {
+		tx.Rollback()
+		return fmt.Errorf("'%s' is not a tracked path", path)
+	}
+	for _, pattern := range patterns {
+		q := `INSERT OR IGNORE INTO ignore_pattern (tracked_path, pattern) VALUES (?, ?)`
+		if _, err = tx.Exec(q, path, pattern); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("could not store ignore pattern: %s", err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit transaction: %s", err)
+	}
+	return nil
+}
+
+// RemoveIgnorePatterns removes ignore patterns from the tracked path.
+// Files that are no longer ignored are indexed by the next rescan.
+func (db *DB) RemoveIgnorePatterns(path string, patterns []string) error {
+	tx, err := db.d.Begin()
+	if err != nil {
+		return fmt.Errorf("could not start transaction: %s", err)
+	}
+	for _, pattern := range patterns {
+		q := `DELETE FROM ignore_pattern WHERE tracked_path = ? AND pattern = ?`
+		if _, err = tx.Exec(q, path, pattern); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("could not remove ignore pattern: %s", err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit transaction: %s", err)
+	}
+	return nil
+}
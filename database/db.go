@@ -4,8 +4,6 @@ import (
 	"database/sql"
 	"fmt"
 	"sync"
-
-	_ "github.com/mattn/go-sqlite3"
 )
 
 // DB represents a database connection. It is not safe for asynchronous
@@ -13,6 +11,7 @@ import (
 // series only.
 type DB struct {
 	d      *sql.DB
+	file   string
 	txLock *sync.Mutex
 	tx     *sql.Tx
 }
@@ -20,9 +19,24 @@ type DB struct {
 // NewDB creates a new database by initializing or updating the schema
 // and returns the ready to use database.
 func NewDB(dbFile string) (DB, error) {
-	rawDB, err := sql.Open("sqlite3", dbFile)
+	db, err := OpenDB(dbFile)
+	if err != nil {
+		return db, err
+	}
+	if _, err = db.Migrate(); err != nil {
+		return db, fmt.Errorf("could not update schema: %s", err)
+	}
+	return db, nil
+}
+
+// OpenDB opens the database in dbFile, which is created if it does not
+// exist, without initializing or updating its schema. Migrate must be
+// called before it is used otherwise.
+func OpenDB(dbFile string) (DB, error) {
+	rawDB, err := sql.Open(driverName, dbFile)
 	db := DB{
 		d:      rawDB,
+		file:   dbFile,
 		txLock: &sync.Mutex{},
 	}
 	if err != nil {
@@ -31,38 +45,17 @@ func NewDB(dbFile string) (DB, error) {
 	if _, err = db.d.Exec(`PRAGMA foreign_keys = ON`); err != nil {
 		return db, fmt.Errorf("could not set pragma for foreign keys: %s", err)
 	}
-	if err = db.updateSchema(); err != nil {
-		return db, fmt.Errorf("could not update schema: %s", err)
+	// The full-text search of documents and file names needs FTS5, which
+	// go-sqlite3 only includes with the sqlite_fts5 build tag:
+	var fts5 bool
+	if err = db.d.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&fts5); err != nil {
+		return db, fmt.Errorf("could not check for FTS5 support: %s", err)
+	} else if !fts5 {
+		return db, fmt.Errorf("SQLite lacks the FTS5 extension; build den with '-tags sqlite_fts5'")
 	}
 	return db, nil
 }
 
-func (db DB) updateSchema() error {
-	version, err := db.schemaVersion()
-	if err != nil {
-		return fmt.Errorf("could not find database version: %s", err)
-	}
-	tx, err := db.d.Begin()
-	if err != nil {
-		return fmt.Errorf("could not start transaction for schema updates: %s", err)
-	}
-	switch version {
-	case 0:
-		if _, err = tx.Exec(schemaV1); err != nil {
-			_ = tx.Rollback()
-			return fmt.Errorf("could not update database schema to version 1: %s", err)
-		}
-		fallthrough
-	case 1:
-		if err = tx.Commit(); err != nil {
-			return fmt.Errorf("could not commit schema update transaction: %s", err)
-		}
-		return nil
-	}
-	_ = tx.Rollback()
-	return fmt.Errorf("unknown database version '%d'", version)
-}
-
 func (db DB) schemaVersion() (int, error) {
 	rows, err := db.d.Query(`PRAGMA user_version`)
 	if err != nil {
@@ -0,0 +1,36 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestDB returns a new, migrated database in a temporary directory.
+func newTestDB(t *testing.T) DB {
+	t.Helper()
+	db, err := NewDB(filepath.Join(t.TempDir(), "db.sqlite3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.d.Close() })
+	return db
+}
+
+// addFiles adds unspecific files with the given paths to db.
+func addFiles(t *testing.T, db DB, paths ...string) {
+	t.Helper()
+	if err := db.BeginTx(); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		f := &File{Path: path, Size: 1, CreatedGuess: time.Unix(0, 0), Modified: time.Unix(0, 0)}
+		if err := db.AddFile(f); err != nil {
+			_ = db.Rollback()
+			t.Fatal(err)
+		}
+	}
+	if err := db.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,254 @@
+package database
+
+import (
+	"cmp"
+	"database/sql"
+	"fmt"
+	"iter"
+	"slices"
+
+	"github.com/codesoap/den/internal/phash"
+)
+
+type DuplicateFilter struct {
+	FileFilter
+
+	// Category limits the duplicates to one category. It may be empty
+	// or one of "picture", "video", "audio", "document", "archive",
+	// "executable" and "other".
+	Category string
+
+	// Similar groups pictures that look alike instead of files with
+	// identical content. Pictures look alike, if their perceptual hashes
+	// differ in at most Threshold bits. Category must be empty or
+	// "picture" then.
+	Similar   bool
+	Threshold int
+}
+
+// UnhashedDuplicateCandidates returns the paths of all files that have
+// not been hashed yet, but share their size with at least one other
+// file. Only these files can have duplicates, so there is no need to
+// hash the others. Members of archives are not hashed.
+func (db DB) UnhashedDuplicateCandidates() ([]string, error) {
+	q := `SELECT path FROM file ` +
+		`WHERE hash IS NULL AND size > 0 AND archive IS NULL AND size IN (` +
+		`SELECT size FROM file WHERE archive IS NULL GROUP BY size HAVING COUNT(*) > 1` +
+		`)`
+	rows, err := db.d.Query(q)
+	if err != nil {
+		return nil, fmt.Errorf("could not query database: %s", err)
+	}
+	defer rows.Close()
+	paths := make([]string, 0)
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, fmt.Errorf("could not read from database: %s", err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, rows.Err()
+}
+
+// SetHash stores the content hash of the file at path. db.BeginTx must
+// have been called before.
+func (db DB) SetHash(path, hash string) error {
+	_, err := db.tx.Exec(`UPDATE file SET hash = ? WHERE path = ?`, hash, path)
+	return err
+}
+
+// Duplicates returns groups of files with identical content. The
+// groups wasting the most space come first.
+func (db DB) Duplicates(filter DuplicateFilter) iter.Seq2[[]*File, error] {
+	if filter.Similar {
+		return db.similarPictureGroups(filter)
+	}
+	return func(yield func([]*File, error) bool) {
+		cond, err := categoryCondition(filter.Category)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		var args []any
+		filters, args := addFileFilters(cond, args, filter.FileFilter)
+		// A group wastes the size of all but one of its files:
+		q := fileSelect + `, f.hash FROM file f, (` +
+			`SELECT f.hash, COUNT(*) AS n FROM file f WHERE f.hash IS NOT NULL ` + filters +
+			`GROUP BY f.hash HAVING COUNT(*) > 1` +
+			`) g WHERE f.hash = g.hash ` + filters +
+			`ORDER BY f.size * (g.n - 1) DESC, f.hash, f.path `
+		scan := func(rows *sql.Rows) (hashedFile, error) {
+			var r fileRow
+			var hash string
+			err := rows.Scan(append(r.dest(), &hash)...)
+			return hashedFile{r.file(), hash}, err
+		}
+		var group []*File
+		var groupHash string
+		for f, err := range query(db, q, append(args, args...), scan) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if f.hash != groupHash && len(group) > 0 {
+				if !yield(group, nil) {
+					return
+				}
+				group = nil
+			}
+			group = append(group, f.file)
+			groupHash = f.hash
+		}
+		if len(group) > 0 {
+			yield(group, nil)
+		}
+	}
+}
+
+type hashedFile struct {
+	file *File
+	hash string
+}
+
+// categoryCondition returns a condition, which can be appended to a
+// WHERE clause, that limits the files in f to the given category.
+func categoryCondition(category string) (string, error) {
+	switch category {
+	case "":
+		return ``, nil
+	case "picture":
+		return `AND ` + pictureCondition, nil
+	case "video":
+		return `AND ` + videoCondition, nil
+	case "audio":
+		return `AND ` + audioCondition, nil
+	case "document":
+		return `AND ` + documentCondition, nil
+	case "archive":
+		return `AND ` + archiveCondition, nil
+	case "executable":
+		return `AND ` + executableCondition, nil
+	case "other":
+		return `AND ` + otherCondition, nil
+	}
+	return ``, fmt.Errorf("unknown category '%s'", category)
+}
+
+// similarPictureGroups returns groups of pictures that look alike. The
+// largest picture of each group comes first and the groups wasting the
+// most space come first.
+func (db DB) similarPictureGroups(filter DuplicateFilter) iter.Seq2[[]*File, error] {
+	return func(yield func([]*File, error) bool) {
+		if filter.Category != "" && filter.Category != "picture" {
+			yield(nil, fmt.Errorf("only pictures can be similar, not the category '%s'", filter.Category))
+			return
+		}
+		q := fileSelect + `, p.phash FROM file f ` +
+			`INNER JOIN picture p ON p.file = f.id ` +
+			`WHERE p.phash IS NOT NULL `
+		var args []any
+		q, args = addFileFilters(q, args, filter.FileFilter)
+		q += `ORDER BY f.size DESC, f.path `
+		scan := func(rows *sql.Rows) (hashedPicture, error) {
+			var r fileRow
+			var hash int64
+			err := rows.Scan(append(r.dest(), &hash)...)
+			return hashedPicture{r.file(), uint64(hash)}, err
+		}
+		var pictures []hashedPicture
+		for pic, err := range query(db, q, args, scan) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			pictures = append(pictures, pic)
+		}
+		for _, group := range clusterSimilar(pictures, filter.Threshold) {
+			if !yield(group, nil) {
+				return
+			}
+		}
+	}
+}
+
+type hashedPicture struct {
+	file *File
+	hash uint64
+}
+
+// clusterSimilar puts pictures, whose hashes differ in at most
+// threshold bits, into the same group. Pictures are also grouped
+// transitively, so two pictures of a group may differ more. Groups
+// keep the order of pictures and are sorted by the size of all but
+// their first picture.
+func clusterSimilar(pictures []hashedPicture, threshold int) [][]*File {
+	// Union-find with path halving; parent[i] == i for roots.
+	parent := make([]int, len(pictures))
+	for i := range parent {
+		parent[i] = i
+	}
+	root := func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+	var index phash.Index
+	for i, pic := range pictures {
+		index.Add(pic.hash, i)
+	}
+	for i, pic := range pictures {
+		for j := range index.Near(pic.hash, threshold) {
+			// The smaller index stays root, to keep the order.
+			a, b := root(i), root(j)
+			parent[max(a, b)] = min(a, b)
+		}
+	}
+	members := make(map[int][]*File)
+	for i, pic := range pictures {
+		members[root(i)] = append(members[root(i)], pic.file)
+	}
+	var groups [][]*File
+	for i := range pictures {
+		if group := members[i]; len(group) > 1 {
+			groups = append(groups, group)
+		}
+	}
+	slices.SortStableFunc(groups, func(a, b []*File) int {
+		return cmp.Compare(reclaimable(b), reclaimable(a))
+	})
+	return groups
+}
+
+// reclaimable returns the size of all but the first file of group.
+func reclaimable(group []*File) int64 {
+	var size int64
+	for _, f := range group[1:] {
+		size += f.Size
+	}
+	return size
+}
+
+// SimilarPictures returns the pictures that look like the tracked
+// picture at path, most similar first. Pictures look alike, if their
+// perceptual hashes differ in at most threshold bits.
+func (db DB) SimilarPictures(path string, threshold int) iter.Seq2[*Picture, error] {
+	var hash *int64
+	q := `SELECT p.phash FROM picture p INNER JOIN file f ON f.id = p.file WHERE f.path = ?`
+	err := db.d.QueryRow(q, path).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return failed[*Picture](fmt.Errorf("'%s' is not a tracked picture", path))
+	} else if err != nil {
+		return failed[*Picture](fmt.Errorf("could not query database: %s", err))
+	} else if hash == nil {
+		return failed[*Picture](fmt.Errorf("'%s' could not be decoded when it was indexed", path))
+	}
+	q = fileSelect + `, ` + pictureSelect + ` FROM file f ` +
+		`INNER JOIN picture p ON p.file = f.id ` +
+		`WHERE f.path != ? AND hamming(p.phash, ?) <= ? ` +
+		`ORDER BY hamming(p.phash, ?), f.path `
+	args := []any{path, *hash, threshold, *hash}
+	return query(db, q, args, scanPicture)
+}
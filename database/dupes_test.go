@@ -0,0 +1,58 @@
+package database
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDuplicatesOrderedByWastedSpace(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.BeginTx(); err != nil {
+		t.Fatal(err)
+	}
+	files := []struct {
+		path string
+		size int64
+		hash string
+	}{
+		{"/t/big1", 100, "big"},
+		{"/t/big2", 100, "big"},
+		{"/t/small1", 40, "small"},
+		{"/t/small2", 40, "small"},
+		{"/t/small3", 40, "small"},
+		{"/t/small4", 40, "small"},
+		{"/t/single", 1000, "single"},
+	}
+	for _, f := range files {
+		file := &File{Path: f.path, Size: f.size, CreatedGuess: time.Unix(0, 0), Modified: time.Unix(0, 0)}
+		if err := db.AddFile(file); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.SetHash(f.path, f.hash); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	var got [][]string
+	for group, err := range db.Duplicates(DuplicateFilter{}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		var paths []string
+		for _, f := range group {
+			paths = append(paths, f.Path)
+		}
+		got = append(got, paths)
+	}
+	want := [][]string{
+		{"/t/small1", "/t/small2", "/t/small3", "/t/small4"},
+		{"/t/big1", "/t/big2"},
+	}
+	if !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("Duplicates() = %q, want %q", got, want)
+	}
+}
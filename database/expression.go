@@ -0,0 +1,357 @@
+package database
+
+import (
+	"fmt"
+	"iter"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codesoap/den/internal/querylang"
+	"github.com/codesoap/den/internal/units"
+)
+
+type fieldKind int
+
+const (
+	textField fieldKind = iota
+	numberField
+	sizeField
+	dateField
+	durationField
+	resolutionField
+	categoryField
+	pathField
+	tagField
+)
+
+// fields maps the fields of query expressions to their kind and the
+// expressions they compare. A comparison of a field with multiple
+// expressions matches, if it matches any of them.
+var fields = map[string]struct {
+	kind  fieldKind
+	exprs []string
+}{
+	"category":    {categoryField, nil},
+	"path":        {pathField, []string{`f.path`}},
+	"name":        {textField, []string{`f.name`}},
+	"mime":        {textField, []string{`f.mime`}},
+	"tag":         {tagField, nil},
+	"size":        {sizeField, []string{`f.size`}},
+	"modified":    {dateField, []string{`f.modified`}},
+	"created":     {dateField, []string{`COALESCE(f.taken, f.created_guess)`}},
+	"taken":       {dateField, []string{`f.taken`}},
+	"duration":    {durationField, []string{`v.seconds`, `a.seconds`}},
+	"year":        {numberField, []string{`v.year`, `a.year`}},
+	"camera":      {textField, []string{`p.camera`, `v.camera`}},
+	"width":       {numberField, []string{`p.width`, `v.width`}},
+	"height":      {numberField, []string{`p.height`, `v.height`}},
+	"resolution":  {resolutionField, []string{`MIN(p.width, p.height)`, `MIN(v.width, v.height)`}},
+	"fps":         {numberField, []string{`v.fps`}},
+	"codec":       {textField, []string{`v.video_codec`, `v.audio_codec`, `a.codec`}},
+	"bitrate":     {numberField, []string{`v.bitrate`, `a.bitrate`}},
+	"author":      {textField, []string{`a.author`}},
+	"album":       {textField, []string{`a.album`}},
+	"title":       {textField, []string{`a.title`}},
+	"genre":       {textField, []string{`a.genre`}},
+	"arch":        {textField, []string{`x.arch`}},
+	"interpreter": {textField, []string{`x.interpreter`}},
+}
+
+// categoryConditions maps the values of the category field to their
+// conditions on the tables joined by allSelect.
+var categoryConditions = map[string]string{
+	"picture":    `p.file IS NOT NULL`,
+	"video":      `v.file IS NOT NULL`,
+	"audio":      `a.file IS NOT NULL`,
+	"document":   `d.file IS NOT NULL`,
+	"archive":    `r.file IS NOT NULL`,
+	"executable": `x.file IS NOT NULL`,
+	"other": `p.file IS NULL AND v.file IS NULL AND a.file IS NULL ` +
+		`AND d.file IS NULL AND r.file IS NULL AND x.file IS NULL`,
+}
+
+// Query returns all files matching the query expression e and filter,
+// sorted as selected by filter. The entries are of the type matching
+// their category, as with DB.All.
+func (db DB) Query(e querylang.Expr, filter FileFilter) iter.Seq2[Entry, error] {
+	q, args, err := QuerySQL(e, filter)
+	if err != nil {
+		return failed[Entry](err)
+	}
+	return query(db, q, args, scanEntry)
+}
+
+// QuerySQL returns the SQL query and its arguments, that DB.Query runs
+// for e and filter. Errors in e are returned as *querylang.Error.
+func QuerySQL(e querylang.Expr, filter FileFilter) (string, []any, error) {
+	cond, args, err := compile(e)
+	if err != nil {
+		return "", nil, err
+	}
+	q := allSelect + `WHERE ` + cond + ` `
+	q, args = addFileFilters(q, args, filter)
+	return addOrder(q, args, filter, "COALESCE(v.seconds, a.seconds)")
+}
+
+// compile converts e to an SQL condition. Comparisons of values that are
+// unknown, e.g. the camera of a file without one, are NULL, so NOT
+// treats them as false first; `camera!=Canon` thus matches files
+// without a camera, just like `NOT camera=Canon`.
+func compile(e querylang.Expr) (string, []any, error) {
+	switch e := e.(type) {
+	case *querylang.And:
+		return compileBinary(e.Left, e.Right, "AND")
+	case *querylang.Or:
+		return compileBinary(e.Left, e.Right, "OR")
+	case *querylang.Not:
+		cond, args, err := compile(e.X)
+		return `NOT IFNULL(` + cond + `, 0)`, args, err
+	case *querylang.Comparison:
+		if e.Op == "!=" {
+			eq := *e
+			eq.Op = "="
+			cond, args, err := compileComparison(&eq)
+			return `NOT IFNULL(` + cond + `, 0)`, args, err
+		}
+		return compileComparison(e)
+	}
+	panic("unknown expression type")
+}
+
+func compileBinary(left, right querylang.Expr, op string) (string, []any, error) {
+	l, args, err := compile(left)
+	if err != nil {
+		return "", nil, err
+	}
+	r, rargs, err := compile(right)
+	if err != nil {
+		return "", nil, err
+	}
+	return `(` + l + ` ` + op + ` ` + r + `)`, append(args, rargs...), nil
+}
+
+func compileComparison(c *querylang.Comparison) (string, []any, error) {
+	field, ok := fields[c.Field]
+	if !ok {
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		return "", nil, querylang.Errorf(c.FieldPos, "unknown field '%s'; known fields are %s",
+			c.Field, strings.Join(names, ", "))
+	}
+	switch field.kind {
+	case categoryField:
+		if c.Op != ":" && c.Op != "=" {
+			return "", nil, unsupportedOp(c)
+		}
+		cond, ok := categoryConditions[strings.ToLower(c.Value)]
+		if !ok {
+			return "", nil, querylang.Errorf(c.ValuePos, "unknown category '%s'; known categories are "+
+				"picture, video, audio, document, archive, executable and other", c.Value)
+		}
+		return `(` + cond + `)`, nil, nil
+	case tagField:
+		if c.Op != ":" && c.Op != "=" {
+			return "", nil, unsupportedOp(c)
+		}
+		return `EXISTS (SELECT 1 FROM tag t WHERE t.path = f.path AND t.tag = ?)`, []any{c.Value}, nil
+	case textField, pathField:
+		return compileText(c, field.kind, field.exprs)
+	}
+	return compileOrdered(c, field.kind, field.exprs)
+}
+
+// compileText compiles comparisons of text. "=" compares exactly. ":"
+// matches paths by prefix or glob pattern, names like FileFilter.Name
+// and other text like the text filters of PictureFilter, but by
+// substring, unless a regular expression or glob pattern is given.
+func compileText(c *querylang.Comparison, kind fieldKind, exprs []string) (string, []any, error) {
+	if c.Op != ":" && c.Op != "=" {
+		return "", nil, unsupportedOp(c)
+	}
+	if c.Field == "name" && c.Op == ":" {
+		if re, ok := RegexpPattern(c.Value); ok {
+			if _, err := regexp.Compile(re); err != nil {
+				return "", nil, querylang.Errorf(c.ValuePos, "invalid regular expression '%s': %s", re, err)
+			}
+		}
+		cond, args := nameCondition(c.Value)
+		return cond, args, nil
+	} else if kind == textField && c.Op == ":" {
+		pattern := c.Value
+		if _, ok := RegexpPattern(pattern); !ok && !IsGlobPattern(pattern) {
+			pattern = "~" + pattern
+		}
+		conds := make([]string, len(exprs))
+		var args []any
+		for i, expr := range exprs {
+			cond, arg, err := textCondition(expr, pattern)
+			if err != nil {
+				return "", nil, querylang.Errorf(c.ValuePos, "%s", err)
+			}
+			conds[i] = cond
+			args = append(args, arg)
+		}
+		return `(` + strings.Join(conds, ` OR `) + `)`, args, nil
+	}
+	value := c.Value
+	if kind == pathField {
+		var err error
+		if value, err = expandPath(value); err != nil {
+			return "", nil, querylang.Errorf(c.ValuePos, "invalid path '%s': %s", c.Value, err)
+		}
+	}
+	switch {
+	case c.Op == "=":
+		return compileAny(exprs, ` = ?`, value)
+	case IsGlobPattern(c.Value):
+		return compileAny(exprs, ` GLOB ?`, value)
+	}
+	// Like rescans, match the path itself and the paths below it
+	// case-sensitively:
+	lower, upper := below(value)
+	conds := make([]string, len(exprs))
+	var args []any
+	for i, expr := range exprs {
+		conds[i] = expr + ` = ? OR (` + expr + ` > ? AND ` + expr + ` < ?)`
+		args = append(args, value, lower, upper)
+	}
+	return `(` + strings.Join(conds, ` OR `) + `)`, args, nil
+}
+
+// compileOrdered compiles comparisons of numbers, sizes, dates and
+// durations. ":" and "=" match a date within the given range, e.g. a
+// whole year, while "<" and ">" compare to its start or end.
+func compileOrdered(c *querylang.Comparison, kind fieldKind, exprs []string) (string, []any, error) {
+	var low, high *float64
+	var err error
+	if kind == dateField {
+		var since, until *time.Time
+		if since, until, err = units.ParseDateRange(c.Value); err == nil {
+			low, high = unixTime(since), unixTime(until)
+		}
+	} else if from, to, ok := strings.Cut(c.Value, ".."); ok {
+		if c.Op != "in" {
+			return "", nil, querylang.Errorf(c.ValuePos, "ranges are only allowed with 'in'")
+		}
+		if from != "" {
+			low = new(float64)
+			*low, err = parseNumber(kind, from)
+		}
+		if to != "" && err == nil {
+			high = new(float64)
+			*high, err = parseNumber(kind, to)
+		}
+	} else {
+		low = new(float64)
+		*low, err = parseNumber(kind, c.Value)
+		high = low
+	}
+	if err != nil {
+		return "", nil, querylang.Errorf(c.ValuePos, "%s", err)
+	}
+
+	var bound *float64
+	var cond string
+	switch c.Op {
+	case ":", "=", "in":
+		if low == nil && high == nil {
+			return "", nil, querylang.Errorf(c.ValuePos, "empty range")
+		} else if low == nil {
+			return compileAny(exprs, ` <= ?`, *high)
+		} else if high == nil {
+			return compileAny(exprs, ` >= ?`, *low)
+		} else if low == high {
+			return compileAny(exprs, ` = ?`, *low)
+		}
+		return compileAny(exprs, ` BETWEEN ? AND ?`, *low, *high)
+	case "<":
+		bound, cond = low, ` < ?`
+	case "<=":
+		bound, cond = high, ` <= ?`
+	case ">":
+		bound, cond = high, ` > ?`
+	case ">=":
+		bound, cond = low, ` >= ?`
+	default:
+		return "", nil, unsupportedOp(c)
+	}
+	if bound == nil {
+		return "", nil, querylang.Errorf(c.ValuePos, "'%s' cannot be used with open ranges", c.Op)
+	}
+	return compileAny(exprs, cond, *bound)
+}
+
+// compileAny returns a condition, that is true if any of exprs followed
+// by cond is true. Whole numbers are passed to SQLite as integers.
+func compileAny(exprs []string, cond string, args ...any) (string, []any, error) {
+	for i, arg := range args {
+		if f, ok := arg.(float64); ok && f == math.Trunc(f) {
+			args[i] = int64(f)
+		}
+	}
+	conds := make([]string, len(exprs))
+	var allArgs []any
+	for i, expr := range exprs {
+		conds[i] = expr + cond
+		allArgs = append(allArgs, args...)
+	}
+	return `(` + strings.Join(conds, ` OR `) + `)`, allArgs, nil
+}
+
+func parseNumber(kind fieldKind, s string) (float64, error) {
+	switch kind {
+	case sizeField:
+		size, err := units.ParseSize(s)
+		return float64(size), err
+	case resolutionField:
+		r, err := units.ParseResolution(s)
+		return float64(r), err
+	case durationField:
+		if d, err := time.ParseDuration(s); err == nil {
+			return d.Seconds(), nil
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number '%s'", s)
+	}
+	return n, nil
+}
+
+func unixTime(t *time.Time) *float64 {
+	if t == nil {
+		return nil
+	}
+	u := float64(t.Unix())
+	return &u
+}
+
+func unsupportedOp(c *querylang.Comparison) error {
+	return querylang.Errorf(c.FieldPos, "'%s' cannot be used with %s", c.Op, c.Field)
+}
+
+// expandPath makes path absolute. A leading ~ is replaced with the home
+// directory.
+func expandPath(path string) (string, error) {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = home + path[1:]
+	}
+	if IsGlobPattern(path) && !filepath.IsAbs(path) {
+		// Relative patterns match anywhere, e.g. "*.jpg".
+		return path, nil
+	}
+	return filepath.Abs(path)
+}
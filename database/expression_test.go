@@ -0,0 +1,171 @@
+package database
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codesoap/den/internal/querylang"
+)
+
+func TestCompile(t *testing.T) {
+	unix := func(year int) int64 {
+		return time.Date(year, 1, 1, 0, 0, 0, 0, time.Local).Unix()
+	}
+	tests := []struct {
+		expr string
+		cond string
+		args []any
+	}{
+		{`size>10M`, `(f.size > ?)`, []any{int64(10_000_000)}},
+		{`size>=1.5K`, `(f.size >= ?)`, []any{int64(1500)}},
+		{`size<=1KiB`, `(f.size <= ?)`, []any{int64(1024)}},
+		{`size:1K`, `(f.size = ?)`, []any{int64(1000)}},
+		{`size in 1M..2M`, `(f.size BETWEEN ? AND ?)`, []any{int64(1_000_000), int64(2_000_000)}},
+		{`size in ..2M`, `(f.size <= ?)`, []any{int64(2_000_000)}},
+		{`size in 1M..`, `(f.size >= ?)`, []any{int64(1_000_000)}},
+		{`fps=29.97`, `(v.fps = ?)`, []any{29.97}},
+		{`duration>10m`, `(v.seconds > ? OR a.seconds > ?)`, []any{int64(600), int64(600)}},
+		{`duration<90`, `(v.seconds < ? OR a.seconds < ?)`, []any{int64(90), int64(90)}},
+		{`resolution>=4k`, `(MIN(p.width, p.height) >= ? OR MIN(v.width, v.height) >= ?)`,
+			[]any{int64(2160), int64(2160)}},
+		{`modified:2019`, `(f.modified BETWEEN ? AND ?)`, []any{unix(2019), unix(2020) - 1}},
+		{`modified<2019`, `(f.modified < ?)`, []any{unix(2019)}},
+		{`modified<=2019`, `(f.modified <= ?)`, []any{unix(2020) - 1}},
+		{`modified>2019`, `(f.modified > ?)`, []any{unix(2020) - 1}},
+		{`modified in 2019..`, `(f.modified >= ?)`, []any{unix(2019)}},
+		{`created:2019..2020`, `(COALESCE(f.taken, f.created_guess) BETWEEN ? AND ?)`,
+			[]any{unix(2019), unix(2021) - 1}},
+		{`camera:canon`, `(instr(casefold(p.camera), ?) > 0 OR instr(casefold(v.camera), ?) > 0)`,
+			[]any{"canon", "canon"}},
+		{`camera:Ärger`, `(instr(casefold(p.camera), ?) > 0 OR instr(casefold(v.camera), ?) > 0)`,
+			[]any{"ärger", "ärger"}},
+		{`camera=Canon`, `(p.camera = ? OR v.camera = ?)`, []any{"Canon", "Canon"}},
+		{`camera:/^can/`, `(p.camera REGEXP ? OR v.camera REGEXP ?)`, []any{"(?i)^can", "(?i)^can"}},
+		{`camera:"/(?-i)^Can/"`, `(p.camera REGEXP ? OR v.camera REGEXP ?)`, []any{"(?-i)^Can", "(?-i)^Can"}},
+		{`camera:iPhone*`, `(casefold(p.camera) GLOB ? OR casefold(v.camera) GLOB ?)`,
+			[]any{"iphone*", "iphone*"}},
+		{`camera!=Canon`, `NOT IFNULL((p.camera = ? OR v.camera = ?), 0)`, []any{"Canon", "Canon"}},
+		{`mime:image`, `(instr(casefold(f.mime), ?) > 0)`, []any{"image"}},
+		{`name:report`, `(f.id IN (SELECT rowid FROM file_name_text WHERE file_name_text MATCH ?) ` +
+			`AND instr(casefold(f.name), ?) > 0)`, []any{`"report"`, "report"}},
+		{`name:ab`, `instr(casefold(f.name), ?) > 0`, []any{"ab"}},
+		{`name:*.jpg`, `(f.id IN (SELECT rowid FROM file_name_text WHERE file_name_text MATCH ?) ` +
+			`AND casefold(f.name) GLOB ?)`, []any{`".jpg"`, "*.jpg"}},
+		{`name:/^IMG/`, `f.name REGEXP ?`, []any{"(?i)^IMG"}},
+		{`name=a.jpg`, `(f.name = ?)`, []any{"a.jpg"}},
+		{`path:/t/a_b`, `(f.path = ? OR (f.path > ? AND f.path < ?))`, []any{"/t/a_b", "/t/a_b/", "/t/a_b0"}},
+		{`path:/t/`, `(f.path = ? OR (f.path > ? AND f.path < ?))`, []any{"/t", "/t/", "/t0"}},
+		{`path:*.jpg`, `(f.path GLOB ?)`, []any{"*.jpg"}},
+		{`path=/t/a.jpg`, `(f.path = ?)`, []any{"/t/a.jpg"}},
+		{`tag:holiday`, `EXISTS (SELECT 1 FROM tag t WHERE t.path = f.path AND t.tag = ?)`, []any{"holiday"}},
+		{`category:Picture`, `(p.file IS NOT NULL)`, nil},
+		{`NOT category:picture`, `NOT IFNULL((p.file IS NOT NULL), 0)`, nil},
+		{`size>1 OR camera=x size<2`,
+			`((f.size > ?) OR ((p.camera = ? OR v.camera = ?) AND (f.size < ?)))`,
+			[]any{int64(1), "x", "x", int64(2)}},
+		{`(size>1 OR size<2) NOT tag:a`,
+			`(((f.size > ?) OR (f.size < ?)) AND NOT IFNULL(EXISTS (SELECT 1 FROM tag t WHERE t.path = f.path AND t.tag = ?), 0))`,
+			[]any{int64(1), int64(2), "a"}},
+	}
+	for _, test := range tests {
+		e, err := querylang.Parse(test.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q): %s", test.expr, err)
+		}
+		cond, args, err := compile(e)
+		if err != nil {
+			t.Errorf("compile(%q): %s", test.expr, err)
+			continue
+		}
+		if cond != test.cond {
+			t.Errorf("compile(%q) = %s, want %s", test.expr, cond, test.cond)
+		}
+		if !reflect.DeepEqual(args, test.args) {
+			t.Errorf("arguments of %q are %#v, want %#v", test.expr, args, test.args)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		pos  int
+		msg  string
+	}{
+		{`foo:1`, 0, "unknown field 'foo'; known fields are album, arch, author"},
+		{`size:1 foo:1`, 7, "unknown field 'foo'"},
+		{`size:abc`, 5, "invalid size 'abc'"},
+		{`year:abc`, 5, "invalid number 'abc'"},
+		{`modified:yesteryear`, 9, "invalid date 'yesteryear'"},
+		{`size:1..2`, 5, "ranges are only allowed with 'in'"},
+		{`size in ..`, 8, "empty range"},
+		{`modified>2019..`, 9, "'>' cannot be used with open ranges"},
+		{`camera<x`, 0, "'<' cannot be used with camera"},
+		{`tag>x`, 0, "'>' cannot be used with tag"},
+		{`category:foo`, 9, "unknown category 'foo'"},
+		{`category in a..b`, 0, "'in' cannot be used with category"},
+		{`name:"/(/"`, 5, "invalid regular expression"},
+		{`camera:"/(/"`, 7, "invalid regular expression"},
+	}
+	for _, test := range tests {
+		e, err := querylang.Parse(test.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q): %s", test.expr, err)
+		}
+		_, _, err = compile(e)
+		var exprErr *querylang.Error
+		if !errors.As(err, &exprErr) {
+			t.Errorf("compile(%q) returned %v, want *querylang.Error", test.expr, err)
+			continue
+		}
+		if exprErr.Pos != test.pos || !strings.Contains(exprErr.Msg, test.msg) {
+			t.Errorf("compile(%q) failed at %d with %q, want %d and %q",
+				test.expr, exprErr.Pos, exprErr.Msg, test.pos, test.msg)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	db := newTestDB(t)
+	addFiles(t, db, "/t/a_b/report.pdf", "/t/axb/notes.txt", "/t/Raw/IMG_1.jpg")
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{`camera=Canon`, nil},
+		// Files without a camera do not equal Canon:
+		{`camera!=Canon`, []string{"/t/Raw/IMG_1.jpg", "/t/a_b/report.pdf", "/t/axb/notes.txt"}},
+		{`NOT camera=Canon`, []string{"/t/Raw/IMG_1.jpg", "/t/a_b/report.pdf", "/t/axb/notes.txt"}},
+		{`path:/t/a_b`, []string{"/t/a_b/report.pdf"}},
+		{`path:/t/a`, nil},
+		{`path:/t/Raw`, []string{"/t/Raw/IMG_1.jpg"}},
+		{`path:/t/raw`, nil},
+		{`path:/T/Raw`, nil},
+		{`path:/t/Raw/IMG_1.jpg`, []string{"/t/Raw/IMG_1.jpg"}},
+		{`NOT path:/t/RAW`, []string{"/t/Raw/IMG_1.jpg", "/t/a_b/report.pdf", "/t/axb/notes.txt"}},
+		{`name:REP`, []string{"/t/a_b/report.pdf"}},
+		{`name:/^img_\d/`, []string{"/t/Raw/IMG_1.jpg"}},
+		{`name:*.txt OR name:*.pdf`, []string{"/t/a_b/report.pdf", "/t/axb/notes.txt"}},
+		{`category:other NOT path:/t/axb`, []string{"/t/Raw/IMG_1.jpg", "/t/a_b/report.pdf"}},
+	}
+	for _, test := range tests {
+		e, err := querylang.Parse(test.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q): %s", test.expr, err)
+		}
+		var got []string
+		for entry, err := range db.Query(e, FileFilter{Sort: "path"}) {
+			if err != nil {
+				t.Fatalf("Query(%q): %s", test.expr, err)
+			}
+			got = append(got, entry.Base().Path)
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("Query(%q) = %q, want %q", test.expr, got, test.want)
+		}
+	}
+}
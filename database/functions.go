@@ -0,0 +1,112 @@
+package database
+
+import (
+	"database/sql"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/codesoap/den/internal/phash"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// driverName is the name of the SQLite driver, that provides the SQL
+// functions defined by den.
+const driverName = "sqlite3_den"
+
+func init() {
+	sql.Register(driverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			if err := conn.RegisterFunc("distance", distance, true); err != nil {
+				return err
+			}
+			if err := conn.RegisterFunc("hamming", hamming, true); err != nil {
+				return err
+			}
+			if err := conn.RegisterFunc("casefold", casefoldValue, true); err != nil {
+				return err
+			}
+			if err := conn.RegisterFunc("regexp", matchRegexp, true); err != nil {
+				return err
+			}
+			return conn.RegisterFunc("basename", basename, true)
+		},
+	})
+}
+
+// distance returns the great-circle distance in meters between two
+// locations given in degrees. If any argument is not a number, NULL is
+// returned.
+func distance(lat1, lon1, lat2, lon2 any) any {
+	var coordinates [4]float64
+	for i, arg := range []any{lat1, lon1, lat2, lon2} {
+		switch v := arg.(type) {
+		case float64:
+			coordinates[i] = v * math.Pi / 180
+		case int64:
+			coordinates[i] = float64(v) * math.Pi / 180
+		default:
+			return nil
+		}
+	}
+	φ1, λ1, φ2, λ2 := coordinates[0], coordinates[1], coordinates[2], coordinates[3]
+	h := math.Pow(math.Sin((φ2-φ1)/2), 2) +
+		math.Cos(φ1)*math.Cos(φ2)*math.Pow(math.Sin((λ2-λ1)/2), 2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(min(h, 1)))
+}
+
+// hamming returns the number of bits in which the integers a and b
+// differ. If any argument is not an integer, NULL is returned.
+func hamming(a, b any) any {
+	x, ok := a.(int64)
+	y, ok2 := b.(int64)
+	if !ok || !ok2 {
+		return nil
+	}
+	return phash.Distance(uint64(x), uint64(y))
+}
+
+// basename returns the last element of path. For members of archives,
+// this is the last element of their path within the archive.
+func basename(path string) string {
+	return filepath.Base(path)
+}
+
+// casefold maps s to a form, in which strings that only differ in case
+// are equal.
+func casefold(s string) string {
+	return strings.ToLower(strings.ToUpper(s))
+}
+
+// casefoldValue is casefold for SQL values. Values other than text are
+// returned unchanged.
+func casefoldValue(v any) any {
+	if s, ok := v.(string); ok {
+		return casefold(s)
+	}
+	return v
+}
+
+// regexps caches the compiled regular expressions of matchRegexp.
+var regexps sync.Map
+
+// matchRegexp implements the REGEXP operator of SQLite with the syntax
+// of Go's regexp package. If value is not text, NULL is returned.
+func matchRegexp(re string, value any) (any, error) {
+	s, ok := value.(string)
+	if !ok {
+		return nil, nil
+	}
+	compiled, ok := regexps.Load(re)
+	if !ok {
+		r, err := regexp.Compile(re)
+		if err != nil {
+			return nil, err
+		}
+		compiled, _ = regexps.LoadOrStore(re, r)
+	}
+	return compiled.(*regexp.Regexp).MatchString(s), nil
+}
@@ -59,12 +59,21 @@ func (db DB) AddPicture(pic *Picture) error {
 	} else if err != nil {
 		return err
 	}
-	q := `INSERT INTO picture (file, camera) VALUES (?, ?)`
+	q := `INSERT INTO picture (file, camera, latitude, longitude, altitude, width, height, phash) ` +
+		`VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
 	var camera *string
 	if pic.Camera != "" {
 		camera = &pic.Camera
 	}
-	if _, err = db.tx.Exec(q, id, camera); err != nil {
+	latitude, longitude, altitude := locationValues(pic.Location)
+	var phash *int64
+	if pic.PerceptualHash != nil {
+		// SQLite only knows signed integers.
+		h := int64(*pic.PerceptualHash)
+		phash = &h
+	}
+	_, err = db.tx.Exec(q, id, camera, latitude, longitude, altitude, pic.Width, pic.Height, phash)
+	if err != nil {
 		f := "could not add picture for file with ID %d: %s"
 		return fmt.Errorf(f, id, err)
 	}
@@ -80,8 +89,9 @@ func (db DB) AddVideo(vid *Video) error {
 	} else if err != nil {
 		return err
 	}
-	q := `INSERT INTO video (file, seconds, camera, year) ` +
-		`VALUES (?, ?, ?, ?)`
+	q := `INSERT INTO video (file, seconds, camera, year, latitude, longitude, altitude, ` +
+		`width, height, fps, video_codec, audio_codec, bitrate) ` +
+		`VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 	var seconds *int
 	var camera *string
 	if vid.Duration != nil {
@@ -91,7 +101,10 @@ func (db DB) AddVideo(vid *Video) error {
 	if vid.Camera != "" {
 		camera = &vid.Camera
 	}
-	_, err = db.tx.Exec(q, id, seconds, camera, vid.Year)
+	latitude, longitude, altitude := locationValues(vid.Location)
+	_, err = db.tx.Exec(q, id, seconds, camera, vid.Year, latitude, longitude, altitude,
+		vid.Width, vid.Height, vid.FrameRate, nullable(vid.VideoCodec), nullable(vid.AudioCodec),
+		vid.Bitrate)
 	if err != nil {
 		f := "could not add video for file with ID %d: %s"
 		return fmt.Errorf(f, id, err)
@@ -109,8 +122,9 @@ func (db DB) AddAudio(audio *Audio) error {
 	} else if err != nil {
 		return err
 	}
-	q := `INSERT INTO audio (file, seconds, author, year) ` +
-		`VALUES (?, ?, ?, ?)`
+	q := `INSERT INTO audio (file, seconds, author, year, ` +
+		`album_artist, album, title, genre, track, disc, codec, bitrate) ` +
+		`VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 	var seconds *int
 	var author *string
 	if audio.Duration != nil {
@@ -120,7 +134,10 @@ func (db DB) AddAudio(audio *Audio) error {
 	if audio.Author != "" {
 		author = &audio.Author
 	}
-	_, err = db.tx.Exec(q, id, seconds, author, audio.Year)
+	_, err = db.tx.Exec(q, id, seconds, author, audio.Year,
+		nullable(audio.AlbumArtist), nullable(audio.Album),
+		nullable(audio.Title), nullable(audio.Genre), audio.Track, audio.Disc,
+		nullable(audio.Codec), audio.Bitrate)
 	if err != nil {
 		f := "could not add audio for file with ID %d: %s"
 		return fmt.Errorf(f, id, err)
@@ -143,6 +160,75 @@ func (db DB) AddDocument(doc *Document) error {
 		f := "could not add document for file with ID %d: %s"
 		return fmt.Errorf(f, id, err)
 	}
+	if doc.Text != "" {
+		q = `INSERT INTO document_text (rowid, text) VALUES (?, ?)`
+		if _, err = db.tx.Exec(q, id, doc.Text); err != nil {
+			f := "could not add text for document with ID %d: %s"
+			return fmt.Errorf(f, id, err)
+		}
+	}
+	return nil
+}
+
+// AddExecutable adds an executable to the database. If the executable
+// already exists, nothing will be done. db.BeginTx must have been called
+// before.
+func (db DB) AddExecutable(exe *Executable) error {
+	id, err := db.addFile(exe.File)
+	if err == errAlreadyExists {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	q := `INSERT INTO executable (file, arch, linking, interpreter) ` +
+		`VALUES (?, ?, ?, ?)`
+	_, err = db.tx.Exec(q, id, nullable(exe.Arch), nullable(exe.Linking),
+		nullable(exe.Interpreter))
+	if err != nil {
+		f := "could not add executable for file with ID %d: %s"
+		return fmt.Errorf(f, id, err)
+	}
+	return nil
+}
+
+// AddArchive adds an archive and its members to the database. If the
+// archive already exists, nothing will be done. db.BeginTx must have
+// been called before.
+func (db DB) AddArchive(arc *Archive) error {
+	id, err := db.addFile(arc.File)
+	if err == errAlreadyExists {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	q := `INSERT INTO archive (file) VALUES (?)`
+	if _, err = db.tx.Exec(q, id); err != nil {
+		f := "could not add archive for file with ID %d: %s"
+		return fmt.Errorf(f, id, err)
+	}
+	for _, member := range arc.Members {
+		member.Base().archive = id
+		switch m := member.(type) {
+		case *Picture:
+			err = db.AddPicture(m)
+		case *Video:
+			err = db.AddVideo(m)
+		case *Audio:
+			err = db.AddAudio(m)
+		case *Document:
+			err = db.AddDocument(m)
+		case *Archive:
+			err = db.AddArchive(m)
+		case *Executable:
+			err = db.AddExecutable(m)
+		case *File:
+			err = db.AddFile(m)
+		}
+		if err != nil {
+			f := "could not add member '%s': %s"
+			return fmt.Errorf(f, member.Base().Path, err)
+		}
+	}
 	return nil
 }
 
@@ -157,16 +243,36 @@ func (db DB) AddFile(file *File) error {
 	return err
 }
 
+// nullable returns nil for empty strings, so that they are stored as
+// NULL.
+func nullable(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
 func (db DB) addFile(file *File) (int64, error) {
-	q := `INSERT INTO file (path, size, created_guess, modified, mime) ` +
-		`VALUES (?, ?, ?, ?, ?) ` +
+	q := `INSERT INTO file (path, name, size, created_guess, modified, mime, archive, taken) ` +
+		`VALUES (?, ?, ?, ?, ?, ?, ?, ?) ` +
 		`ON CONFLICT (path) DO NOTHING`
+	var archive, taken *int64
+	if file.archive != 0 {
+		archive = &file.archive
+	}
+	if file.Taken != nil {
+		t := file.Taken.Unix()
+		taken = &t
+	}
 	res, err := db.tx.Exec(q,
 		file.Path,
+		basename(file.Path),
 		file.Size,
 		file.CreatedGuess.Unix(),
 		file.Modified.Unix(),
 		file.MIME,
+		archive,
+		taken,
 	)
 	if err != nil {
 		return 0, fmt.Errorf("could not create file entry: %s", err)
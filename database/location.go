@@ -0,0 +1,40 @@
+package database
+
+import "math"
+
+// earthRadius is the mean radius of the earth in meters.
+const earthRadius = 6_371_000
+
+// addLocationFilters adds the filters for the location columns of the
+// table with the given alias to q.
+func addLocationFilters(q string, args []any, alias string, geotagged bool, near *Area) (string, []any) {
+	if geotagged {
+		q += `AND ` + alias + `.latitude IS NOT NULL `
+	}
+	if near != nil {
+		// The latitude is limited first, so that the index can be used.
+		delta := near.Radius / earthRadius * 180 / math.Pi
+		q += `AND ` + alias + `.latitude BETWEEN ? AND ? ` +
+			`AND distance(` + alias + `.latitude, ` + alias + `.longitude, ?, ?) <= ? `
+		args = append(args, near.Latitude-delta, near.Latitude+delta,
+			near.Latitude, near.Longitude, near.Radius)
+	}
+	return q, args
+}
+
+// toLocation returns nil, if latitude or longitude is unknown.
+func toLocation(latitude, longitude, altitude *float64) *Location {
+	if latitude == nil || longitude == nil {
+		return nil
+	}
+	return &Location{Latitude: *latitude, Longitude: *longitude, Altitude: altitude}
+}
+
+// locationValues returns the values for the latitude, longitude and
+// altitude columns.
+func locationValues(l *Location) (latitude, longitude, altitude *float64) {
+	if l == nil {
+		return nil, nil, nil
+	}
+	return &l.Latitude, &l.Longitude, l.Altitude
+}
@@ -0,0 +1,129 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// migration updates the schema from the previous version to version.
+// Each migration runs in its own transaction; its schema also sets
+// user_version.
+type migration struct {
+	version     int
+	description string
+	schema      string
+}
+
+// migrations holds all schema updates in order. New migrations are
+// appended with the next version.
+var migrations = []migration{
+	{1, "create the tables for files, pictures, videos and audio", schemaV1},
+	{2, "add the hashes of files", schemaV2},
+	{3, "add the full-text index of documents", schemaV3},
+	{4, "add tags", schemaV4},
+	{5, "add archives and their members", schemaV5},
+	{6, "add executables", schemaV6},
+	{7, "add the metadata of audio files", schemaV7},
+	{8, "add the time pictures and videos were taken", schemaV8},
+	{9, "add the location of pictures and videos", schemaV9},
+	{10, "add the resolution, frame rate, codecs and bitrate", schemaV10},
+	{11, "add the perceptual hashes of pictures", schemaV11},
+	{12, "add the ignore patterns of tracked paths", schemaV12},
+	{13, "add the walk options of tracked paths", schemaV13},
+	{14, "add the names of files", schemaV14},
+	{15, "add the trigram index of file names", schemaV15},
+	{16, "remove the trailing space from the MIME type of OpenDocument presentations", schemaV16},
+}
+
+// SchemaVersion is the newest version of the schema, that is known to
+// this version of den.
+var SchemaVersion = migrations[len(migrations)-1].version
+
+func init() {
+	for i, m := range migrations {
+		if m.version != i+1 {
+			panic(fmt.Sprintf("migration %d has version %d", i+1, m.version))
+		}
+	}
+}
+
+// Migration is a pending update of the database schema.
+type Migration struct {
+	// Version is the schema version after the migration.
+	Version     int
+	Description string
+}
+
+// PendingMigrations returns the migrations Migrate would run, in order.
+func (db DB) PendingMigrations() ([]Migration, error) {
+	version, err := db.supportedSchemaVersion()
+	if err != nil {
+		return nil, err
+	}
+	pending := make([]Migration, 0)
+	for _, m := range migrations[version:] {
+		pending = append(pending, Migration{m.version, m.description})
+	}
+	return pending, nil
+}
+
+// Migrate updates the schema to SchemaVersion. Before an existing
+// database is updated, a copy of it is stored next to it; its path is
+// returned. If no copy was made, because the schema is up to date or
+// the database is new, the returned path is empty.
+func (db DB) Migrate() (string, error) {
+	version, err := db.supportedSchemaVersion()
+	if err != nil || version == SchemaVersion {
+		return "", err
+	}
+	var backup string
+	if version > 0 && db.file != "" {
+		if backup, err = db.backup(version); err != nil {
+			return "", err
+		}
+	}
+	for _, m := range migrations[version:] {
+		tx, err := db.d.Begin()
+		if err != nil {
+			return backup, fmt.Errorf("could not start transaction for schema update: %s", err)
+		}
+		if _, err = tx.Exec(m.schema); err != nil {
+			_ = tx.Rollback()
+			return backup, fmt.Errorf("could not update database schema to version %d: %s", m.version, err)
+		}
+		if err = tx.Commit(); err != nil {
+			return backup, fmt.Errorf("could not commit schema update to version %d: %s", m.version, err)
+		}
+	}
+	return backup, nil
+}
+
+// supportedSchemaVersion returns the schema version of db or an error,
+// if it was created by a newer version of den.
+func (db DB) supportedSchemaVersion() (int, error) {
+	version, err := db.schemaVersion()
+	if err != nil {
+		return 0, fmt.Errorf("could not find database version: %s", err)
+	}
+	if version > SchemaVersion {
+		return 0, fmt.Errorf("the database has schema version %d, but this version of den only "+
+			"supports up to version %d; update den or use a backup of the database", version, SchemaVersion)
+	}
+	return version, nil
+}
+
+// backup copies the database, which has the given schema version, to
+// a file next to it and returns the path of the copy. An older copy of
+// the same version is replaced.
+func (db DB) backup(version int) (string, error) {
+	path := fmt.Sprintf("%s.v%d.bak", db.file, version)
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("could not remove old backup: %s", err)
+	}
+	if _, err := db.d.Exec(`VACUUM INTO ?`, path); err != nil {
+		return "", fmt.Errorf("could not back up database to '%s': %s", path, err)
+	}
+	return path, nil
+}
@@ -0,0 +1,349 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// snapshot creates a database file with the schema of the given version
+// and returns it opened, but not migrated.
+func snapshot(t *testing.T, version int, statements ...string) DB {
+	t.Helper()
+	db, err := OpenDB(filepath.Join(t.TempDir(), "db.sqlite3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.d.Close() })
+	for _, m := range migrations[:version] {
+		if _, err = db.d.Exec(m.schema); err != nil {
+			t.Fatalf("could not create schema version %d: %s", m.version, err)
+		}
+	}
+	for _, s := range statements {
+		if _, err = db.d.Exec(s); err != nil {
+			t.Fatalf("could not fill snapshot of version %d: %s", version, err)
+		}
+	}
+	return db
+}
+
+// schema returns the definitions of all tables, indexes and triggers of
+// db.
+func schema(t *testing.T, db DB) []string {
+	t.Helper()
+	rows, err := db.d.Query(`SELECT type || ' ' || name || ': ' || COALESCE(sql, '') ` +
+		`FROM sqlite_schema ORDER BY type, name`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var definitions []string
+	for rows.Next() {
+		var definition string
+		if err = rows.Scan(&definition); err != nil {
+			t.Fatal(err)
+		}
+		definitions = append(definitions, definition)
+	}
+	if err = rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return definitions
+}
+
+func queryInt(t *testing.T, db DB, q string) int {
+	t.Helper()
+	var n int
+	if err := db.d.QueryRow(q).Scan(&n); err != nil {
+		t.Fatalf("%s: %s", q, err)
+	}
+	return n
+}
+
+func TestMigrate(t *testing.T) {
+	want := schema(t, snapshot(t, SchemaVersion))
+	tests := []struct {
+		version    int
+		statements []string
+		files      int
+		tags       int
+	}{
+		{0, nil, 0, 0},
+		{1, []string{
+			`INSERT INTO file VALUES (1, '/t/a.jpg', 10, 0, 0, 'image/jpeg')`,
+			`INSERT INTO picture VALUES (1, 'Canon')`,
+			`INSERT INTO file VALUES (2, '/t/b.txt', 20, 0, 0, 'text/plain')`,
+			`INSERT INTO document VALUES (2)`,
+		}, 2, 0},
+		{10, []string{
+			`INSERT INTO file (id, path, size, created_guess, modified, mime) ` +
+				`VALUES (1, '/t/a.jpg', 10, 0, 0, 'image/jpeg')`,
+			`INSERT INTO picture (file, camera, width, height) VALUES (1, 'Canon', 4, 3)`,
+			`INSERT INTO file (id, path, size, created_guess, modified, mime) ` +
+				`VALUES (2, '/t/b.zip', 20, 0, 0, 'application/zip')`,
+			`INSERT INTO archive VALUES (2)`,
+			`INSERT INTO file (id, path, size, created_guess, modified, mime, archive) ` +
+				`VALUES (3, '/t/b.zip!/c.jpg', 30, 0, 0, 'image/jpeg', 2)`,
+			`INSERT INTO tag VALUES ('holiday', '/t/a.jpg')`,
+		}, 3, 1},
+	}
+	for _, test := range tests {
+		db := snapshot(t, test.version, test.statements...)
+		backup, err := db.Migrate()
+		if err != nil {
+			t.Fatalf("could not migrate from version %d: %s", test.version, err)
+		}
+		if got := schema(t, db); !slices.Equal(got, want) {
+			t.Errorf("schema after migrating from version %d differs:\n%s\nwant:\n%s",
+				test.version, strings.Join(got, "\n"), strings.Join(want, "\n"))
+		}
+		if v := queryInt(t, db, `PRAGMA user_version`); v != SchemaVersion {
+			t.Errorf("version after migrating from version %d is %d, want %d", test.version, v, SchemaVersion)
+		}
+		if n := queryInt(t, db, `SELECT COUNT(*) FROM file`); n != test.files {
+			t.Errorf("%d of %d files survived migrating from version %d", n, test.files, test.version)
+		}
+		if n := queryInt(t, db, `SELECT COUNT(*) FROM tag`); n != test.tags {
+			t.Errorf("%d of %d tags survived migrating from version %d", n, test.tags, test.version)
+		}
+
+		if test.version == 0 {
+			if backup != "" {
+				t.Errorf("new database was backed up to %s", backup)
+			}
+			continue
+		}
+		wantBackup := fmt.Sprintf("%s.v%d.bak", db.file, test.version)
+		if backup != wantBackup {
+			t.Errorf("backup of version %d is %s, want %s", test.version, backup, wantBackup)
+		} else if _, err := os.Stat(backup); err != nil {
+			t.Errorf("backup of version %d is missing: %s", test.version, err)
+		} else {
+			old, err := OpenDB(backup)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v := queryInt(t, old, `PRAGMA user_version`); v != test.version {
+				t.Errorf("backup of version %d has version %d", test.version, v)
+			}
+			old.d.Close()
+		}
+		if n := queryInt(t, db, `SELECT COUNT(*) FROM file WHERE name = 'a.jpg'`); n != 1 {
+			t.Errorf("name of files migrated from version %d was not filled", test.version)
+		}
+		if n := queryInt(t, db, `SELECT COUNT(*) FROM file_name_text WHERE name MATCH '"a.j"'`); n != 1 {
+			t.Errorf("files migrated from version %d are missing in the index of names", test.version)
+		}
+	}
+}
+
+// reindexed returns the IDs of the files marked for reindexing.
+func reindexed(t *testing.T, db DB) []int {
+	t.Helper()
+	rows, err := db.d.Query(`SELECT file FROM reindex ORDER BY file`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+func TestMigrateMarksFilesForReindexing(t *testing.T) {
+	db := snapshot(t, 10,
+		`INSERT INTO file (id, path, size, created_guess, modified, mime) `+
+			`VALUES (1, '/t/a.jpg', 10, 0, 0, 'image/jpeg')`,
+		`INSERT INTO picture (file) VALUES (1)`,
+		`INSERT INTO file (id, path, size, created_guess, modified, mime) `+
+			`VALUES (2, '/t/b.zip', 20, 0, 0, 'application/zip')`,
+		`INSERT INTO archive VALUES (2)`,
+		`INSERT INTO file (id, path, size, created_guess, modified, mime, archive) `+
+			`VALUES (3, '/t/b.zip!/c.jpg', 30, 0, 0, 'image/jpeg', 2)`,
+		`INSERT INTO picture (file) VALUES (3)`,
+		`INSERT INTO file (id, path, size, created_guess, modified, mime) `+
+			`VALUES (5, '/t/e.mp3', 50, 0, 0, 'audio/mpeg')`,
+		`INSERT INTO audio (file) VALUES (5)`,
+	)
+	if _, err := db.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	// Version 11 reindexes pictures, also those within archives:
+	if got, want := reindexed(t, db), []int{1, 2}; !slices.Equal(got, want) {
+		t.Errorf("files marked for reindexing are %v, want %v", got, want)
+	}
+}
+
+// schemaV2Snapshot is the schema den wrote at version 2. Unlike the
+// snapshots of TestMigrate, it does not change if the migrations are
+// edited.
+const schemaV2Snapshot = `
+CREATE TABLE tracked_path(
+	path TEXT NOT NULL,
+	UNIQUE(path)
+);
+
+CREATE TABLE file(
+	id            INTEGER PRIMARY KEY,
+	path          TEXT NOT NULL,
+	size          INTEGER NOT NULL,
+	created_guess INTEGER NOT NULL,
+	modified      INTEGER NOT NULL,
+	mime          TEXT NOT NULL,
+	UNIQUE(path)
+);
+CREATE INDEX file_path ON file(path);
+CREATE INDEX file_created_guess ON file(created_guess);
+CREATE INDEX file_modified ON file(modified);
+CREATE INDEX file_mime ON file(mime);
+
+CREATE TABLE picture(
+	file   INTEGER PRIMARY KEY,
+	camera TEXT,
+	FOREIGN KEY(file) REFERENCES file(id) ON DELETE CASCADE
+);
+CREATE INDEX picture_camera ON picture(camera);
+
+CREATE TABLE video(
+	file    INTEGER PRIMARY KEY,
+	seconds INTEGER,
+	camera  TEXT,
+	year    INTEGER,
+	FOREIGN KEY(file) REFERENCES file(id) ON DELETE CASCADE
+);
+CREATE INDEX video_seconds ON video(seconds);
+CREATE INDEX video_camera ON video(camera);
+CREATE INDEX video_year ON video(year);
+
+CREATE TABLE audio(
+	file    INTEGER PRIMARY KEY,
+	seconds INTEGER,
+	author  TEXT,
+	year    INTEGER,
+	FOREIGN KEY(file) REFERENCES file(id) ON DELETE CASCADE
+);
+CREATE INDEX audio_seconds ON audio(seconds);
+CREATE INDEX audio_author ON audio(author);
+CREATE INDEX audio_year ON audio(year);
+
+CREATE TABLE document(
+	file INTEGER PRIMARY KEY,
+	FOREIGN KEY(file) REFERENCES file(id) ON DELETE CASCADE
+);
+
+ALTER TABLE file ADD COLUMN hash TEXT;
+CREATE INDEX file_hash ON file(hash);
+CREATE INDEX file_size ON file(size);
+
+PRAGMA user_version = 2;
+`
+
+func TestMigrateSchemaV2Snapshot(t *testing.T) {
+	db := snapshot(t, 0, schemaV2Snapshot,
+		`INSERT INTO tracked_path VALUES ('/t')`,
+		`INSERT INTO file VALUES (1, '/t/a.jpg', 10, 0, 0, 'image/jpeg', 'aa')`,
+		`INSERT INTO picture VALUES (1, 'Canon')`,
+		`INSERT INTO file VALUES (2, '/t/b.txt', 20, 0, 0, 'text/plain', NULL)`,
+		`INSERT INTO document VALUES (2)`,
+		`INSERT INTO file VALUES (3, '/t/c.mp3', 30, 0, 0, 'audio/mpeg', NULL)`,
+		`INSERT INTO audio VALUES (3, 60, 'Someone', 2001)`,
+		`INSERT INTO file VALUES (4, '/t/d.mp4', 40, 0, 0, 'video/mp4', NULL)`,
+		`INSERT INTO video VALUES (4, 90, 'Canon', 2002)`,
+		`INSERT INTO file VALUES (5, '/t/e.zip', 50, 0, 0, 'application/zip', NULL)`,
+		`INSERT INTO file VALUES (6, '/t/f.odp', 60, 0, 0, 'application/vnd.oasis.opendocument.presentation ', NULL)`,
+		`INSERT INTO document VALUES (6)`,
+	)
+	backup, err := db.Migrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backup != db.file+".v2.bak" {
+		t.Errorf("backup of version 2 is %s", backup)
+	}
+	if got, want := schema(t, db), schema(t, snapshot(t, SchemaVersion)); !slices.Equal(got, want) {
+		t.Errorf("schema after migrating from version 2 differs:\n%s\nwant:\n%s",
+			strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if n := queryInt(t, db, `SELECT COUNT(*) FROM file f JOIN picture p ON p.file = f.id `+
+		`WHERE f.hash = 'aa' AND p.camera = 'Canon'`); n != 1 {
+		t.Error("the hash or camera of a picture was lost")
+	}
+	if n := queryInt(t, db, `SELECT COUNT(*) FROM tracked_path`); n != 1 {
+		t.Errorf("%d tracked paths survived, want 1", n)
+	}
+	if n := queryInt(t, db, `SELECT COUNT(*) FROM file `+
+		`WHERE mime = 'application/vnd.oasis.opendocument.presentation'`); n != 1 {
+		t.Error("the MIME type of an OpenDocument presentation was not corrected")
+	}
+	// Every file lacks information that later versions gather:
+	if got, want := reindexed(t, db), []int{1, 2, 3, 4, 5, 6}; !slices.Equal(got, want) {
+		t.Errorf("files marked for reindexing are %v, want %v", got, want)
+	}
+}
+
+func TestPendingMigrations(t *testing.T) {
+	db := snapshot(t, 12)
+	pending, err := db.PendingMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var versions []int
+	for _, m := range pending {
+		versions = append(versions, m.Version)
+		if m.Description == "" {
+			t.Errorf("migration to version %d has no description", m.Version)
+		}
+	}
+	var want []int
+	for v := 13; v <= SchemaVersion; v++ {
+		want = append(want, v)
+	}
+	if !slices.Equal(versions, want) {
+		t.Errorf("pending migrations of version 12 are %v, want %v", versions, want)
+	}
+	if v := queryInt(t, db, `PRAGMA user_version`); v != 12 {
+		t.Errorf("listing pending migrations changed the version to %d", v)
+	}
+	if _, err := os.Stat(db.file + ".v12.bak"); err == nil {
+		t.Errorf("listing pending migrations created a backup")
+	}
+
+	if _, err = db.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	if pending, err = db.PendingMigrations(); err != nil {
+		t.Fatal(err)
+	} else if len(pending) != 0 {
+		t.Errorf("migrations pending after migrating: %v", pending)
+	}
+}
+
+func TestMigrateRejectsNewerVersion(t *testing.T) {
+	db := snapshot(t, SchemaVersion, `PRAGMA user_version = 1000`)
+	if _, err := db.PendingMigrations(); err == nil || !strings.Contains(err.Error(), "schema version 1000") {
+		t.Errorf("PendingMigrations of newer database returned %v", err)
+	}
+	backup, err := db.Migrate()
+	if err == nil || !strings.Contains(err.Error(), "schema version 1000") {
+		t.Errorf("Migrate of newer database returned %v", err)
+	}
+	if backup != "" {
+		t.Errorf("Migrate of newer database made backup %s", backup)
+	}
+	if v := queryInt(t, db, `PRAGMA user_version`); v != 1000 {
+		t.Errorf("Migrate of newer database changed its version to %d", v)
+	}
+}
@@ -1,370 +1,424 @@
 package database
 
-import (
-	"fmt"
-	"sync"
-)
-
-type intcount struct{ num, count int }
-type strcount struct {
-	str   string
-	count int
+import "fmt"
+
+// YearCount is the number of files for a year. For decades, Year is the
+// first year of the decade.
+type YearCount struct{ Year, Count int }
+
+// ValueCount is the number of files with a specific value, e.g. the
+// number of pictures taken with a camera.
+type ValueCount struct {
+	Value string
+	Count int
+}
+
+// ResolutionCount is the number of files whose shorter side is at least
+// Resolution pixels long.
+type ResolutionCount struct{ Resolution, Count int }
+
+// commonResolutions are the resolutions ResolutionCounts are gathered
+// for.
+var commonResolutions = []int{720, 1080, 1440, 2160, 4320}
+
+// SizeCount is the number of files that are at least Min and at most
+// Max bytes large. Max is nil for the largest files.
+type SizeCount struct {
+	Min   int64
+	Max   *int64
+	Count int
+}
+
+// sizeBounds are the bounds of the size ranges SizeCounts are gathered
+// for; one range per order of magnitude.
+var sizeBounds = []int64{0, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8, 1e9, 1e10, 1e11, 1e12}
+
+// FileDetails holds the facets that are available for all categories.
+type FileDetails struct {
+	// CreatedYears holds the number of files per year of creation. The
+	// time a file was taken is used instead, if it is known.
+	CreatedYears []YearCount
+
+	// Tags holds the number of files per tag.
+	Tags []ValueCount
+
+	// Sizes holds the number of files per order of magnitude of their
+	// size. Ranges no file falls into are omitted.
+	Sizes []SizeCount
 }
 
-func (db DB) PrintPicturesDetails() error {
+type PictureDetails struct {
+	FileDetails
+	Cameras []ValueCount
+
+	// Geotagged is the number of pictures with a known location.
+	Geotagged int
+
+	// Orientations holds the number of pictures per orientation, i.e.
+	// "landscape", "portrait" or "square".
+	Orientations []ValueCount
+	Resolutions  []ResolutionCount
+}
+
+type ExecutableDetails struct {
+	FileDetails
+	Archs        []ValueCount
+	Interpreters []ValueCount
+}
+
+type VideoDetails struct {
+	FileDetails
+
+	// Short and Long are the numbers of videos up to and at least 10
+	// minutes long.
+	Short, Long int
+
+	Cameras         []ValueCount
+	RecordedDecades []YearCount
+
+	// Geotagged is the number of videos with a known location.
+	Geotagged int
+
+	// Orientations holds the number of videos per orientation, i.e.
+	// "landscape", "portrait" or "square".
+	Orientations []ValueCount
+	Resolutions  []ResolutionCount
+
+	VideoCodecs, AudioCodecs []ValueCount
+
+	// FrameRates holds the number of videos per frame rate, rounded to
+	// whole frames per second.
+	FrameRates []ValueCount
+}
+
+type AudioDetails struct {
+	FileDetails
+
+	// Short and Long are the numbers of audio files up to and at least
+	// 10 minutes long.
+	Short, Long int
+
+	Authors         []ValueCount
+	RecordedDecades []YearCount
+	Genres          []ValueCount
+	Codecs          []ValueCount
+
+	// Albums holds the number of files per album, sorted by artist.
+	Albums []AlbumCount
+}
+
+// AlbumCount is the number of files of an album. Artist is the album
+// artist or, if unknown, the author. It is empty, if neither is known.
+type AlbumCount struct {
+	Artist, Album string
+	Count         int
+}
+
+// fileDetails gathers the facets for all files matching condition.
+func (db DB) fileDetails(condition string) (FileDetails, error) {
+	details := FileDetails{}
+	var err error
 	q := `SELECT ` +
-		`strftime('%Y', datetime(created_guess, 'unixepoch', 'localtime')) AS year, ` +
+		`strftime('%Y', datetime(COALESCE(taken, created_guess), 'unixepoch', 'localtime')) AS year, ` +
 		`COUNT(*) ` +
 		`FROM file f ` +
-		`WHERE EXISTS (SELECT 1 FROM picture p WHERE p.file = f.id) ` +
+		`WHERE f.archive IS NULL AND ` + condition +
 		`GROUP BY year ` +
 		`ORDER BY year `
-	res := make(chan intcount)
-	var wg sync.WaitGroup
-	wg.Go(func() {
-		for i := 0; true; i++ {
-			r, ok := <-res
-			if !ok {
-				return
-			} else if i == 0 {
-				fmt.Println("Pictures can be filtered by the year of creation:")
-			}
-			f := "\t`-c %d` lists all pictures created in %d (%d pictures).\n"
-			fmt.Printf(f, r.num, r.num, r.count)
+	if details.CreatedYears, err = db.yearCounts(q); err != nil {
+		return details, err
+	}
+	q = `SELECT t.tag, COUNT(*) FROM tag t ` +
+		`INNER JOIN file f ON f.path = t.path ` +
+		`WHERE f.archive IS NULL AND ` + condition +
+		`GROUP BY t.tag ` +
+		`ORDER BY t.tag `
+	if details.Tags, err = db.valueCounts(q); err != nil {
+		return details, err
+	}
+	details.Sizes, err = db.sizeCounts(condition)
+	return details, err
+}
+
+// sizeCounts counts the files matching condition per range of
+// sizeBounds. Adjacent ranges share their bound, so that each count
+// matches the files selected by filtering for the range.
+func (db DB) sizeCounts(condition string) ([]SizeCount, error) {
+	counts := make([]SizeCount, 0)
+	for i, bound := range sizeBounds {
+		c := SizeCount{Min: bound}
+		q := `SELECT COUNT(*) FROM file f ` +
+			`WHERE f.archive IS NULL AND ` + condition +
+			`AND f.size >= ? `
+		args := []any{bound}
+		if i+1 < len(sizeBounds) {
+			max := sizeBounds[i+1]
+			c.Max = &max
+			q += `AND f.size <= ? `
+			args = append(args, max)
+		}
+		if err := db.d.QueryRow(q, args...).Scan(&c.Count); err != nil {
+			return nil, fmt.Errorf("could not get file count: %s", err)
+		} else if c.Count > 0 {
+			counts = append(counts, c)
 		}
-	})
-	if err := db.getIntCounts(q, res); err != nil {
-		return err
 	}
-	wg.Wait()
+	return counts, nil
+}
 
-	q = `SELECT camera, COUNT(*) FROM picture ` +
+func (db DB) PicturesDetails() (PictureDetails, error) {
+	details := PictureDetails{}
+	var err error
+	if details.FileDetails, err = db.fileDetails(pictureCondition); err != nil {
+		return details, err
+	}
+	q := `SELECT camera, COUNT(*) FROM picture ` +
 		`WHERE camera IS NOT NULL ` +
 		`GROUP BY camera ` +
 		`ORDER BY camera `
-	res2 := make(chan strcount)
-	wg.Go(func() {
-		for i := 0; true; i++ {
-			r, ok := <-res2
-			if !ok {
-				return
-			} else if i == 0 {
-				fmt.Println("Pictures can be filtered by the camera they were created with:")
-			}
-			f := "\t`-camera '%s'` lists all pictures created with that camera (%d pictures).\n"
-			fmt.Printf(f, r.str, r.count)
-		}
-	})
-	if err := db.getStringCounts(q, res2); err != nil {
-		return err
+	if details.Cameras, err = db.valueCounts(q); err != nil {
+		return details, err
 	}
-	wg.Wait()
-	return nil
+	row := db.d.QueryRow(`SELECT COUNT(*) FROM picture WHERE latitude IS NOT NULL`)
+	if err = row.Scan(&details.Geotagged); err != nil {
+		return details, fmt.Errorf("could not get picture count: %s", err)
+	}
+	if details.Orientations, err = db.orientationCounts("picture"); err != nil {
+		return details, err
+	}
+	details.Resolutions, err = db.resolutionCounts("picture")
+	return details, err
 }
 
-func (db DB) PrintVideosDetails() error {
-	q := `SELECT ` +
-		`strftime('%Y', datetime(created_guess, 'unixepoch', 'localtime')) AS year, ` +
-		`COUNT(*) ` +
-		`FROM file f ` +
-		`WHERE EXISTS (SELECT 1 FROM video v WHERE v.file = f.id) ` +
-		`GROUP BY year ` +
-		`ORDER BY year `
-	res := make(chan intcount)
-	var wg sync.WaitGroup
-	wg.Go(func() {
-		for i := 0; true; i++ {
-			r, ok := <-res
-			if !ok {
-				return
-			} else if i == 0 {
-				fmt.Println("Videos can be filtered by the year of file creation:")
-			}
-			f := "\t`-c %d` lists all videos created in %d (%d videos).\n"
-			fmt.Printf(f, r.num, r.num, r.count)
-		}
-	})
-	if err := db.getIntCounts(q, res); err != nil {
-		return err
+func (db DB) VideosDetails() (VideoDetails, error) {
+	details := VideoDetails{}
+	var err error
+	if details.FileDetails, err = db.fileDetails(videoCondition); err != nil {
+		return details, err
 	}
-	wg.Wait()
-
-	fmt.Printf("Videos can be filtered by their length:\n")
 	row := db.d.QueryRow(`SELECT COUNT(*) FROM video WHERE seconds <= 600`)
-	var cnt int
-	if err := row.Scan(&cnt); err != nil {
-		return fmt.Errorf("could not get video count: %s", err)
+	if err = row.Scan(&details.Short); err != nil {
+		return details, fmt.Errorf("could not get video count: %s", err)
 	}
-	fmt.Printf("\t`-durmax 10m` lists all videos up to 10 minutes long (%d videos).\n", cnt)
 	row = db.d.QueryRow(`SELECT COUNT(*) FROM video WHERE seconds >= 600`)
-	if err := row.Scan(&cnt); err != nil {
-		return fmt.Errorf("could not get video count: %s", err)
+	if err = row.Scan(&details.Long); err != nil {
+		return details, fmt.Errorf("could not get video count: %s", err)
 	}
-	fmt.Printf("\t`-durmin 10m` lists all videos at least 10 minutes long (%d videos).\n", cnt)
-
-	q = `SELECT camera, COUNT(*) FROM video ` +
+	q := `SELECT camera, COUNT(*) FROM video ` +
 		`WHERE camera IS NOT NULL ` +
 		`GROUP BY camera ` +
 		`ORDER BY camera `
-	res2 := make(chan strcount)
-	wg.Go(func() {
-		for i := 0; true; i++ {
-			r, ok := <-res2
-			if !ok {
-				return
-			} else if i == 0 {
-				fmt.Println("Videos can be filtered by the camera they were created with:")
-			}
-			f := "\t`-camera '%s'` lists all videos created with that camera (%d videos).\n"
-			fmt.Printf(f, r.str, r.count)
-		}
-	})
-	if err := db.getStringCounts(q, res2); err != nil {
-		return err
+	if details.Cameras, err = db.valueCounts(q); err != nil {
+		return details, err
 	}
-	wg.Wait()
-
-	q = `SELECT ` +
-		`CAST((year/10)*10 AS TEXT) || '-' || CAST(((year/10)*10+9) AS TEXT) AS decade,` +
-		`COUNT(*) ` +
+	q = `SELECT (year/10)*10 AS decade, COUNT(*) ` +
 		`FROM video ` +
 		`WHERE year IS NOT NULL ` +
 		`GROUP BY decade ` +
 		`ORDER BY decade `
-	res2 = make(chan strcount)
-	wg.Go(func() {
-		for i := 0; true; i++ {
-			r, ok := <-res2
-			if !ok {
-				return
-			} else if i == 0 {
-				fmt.Println("Videos can be filtered by the year they were recorded:")
-			}
-			f := "\t`-year %s` lists all videos recorded in that decade (%d videos).\n"
-			fmt.Printf(f, r.str, r.count)
-		}
-	})
-	if err := db.getStringCounts(q, res2); err != nil {
-		return err
+	if details.RecordedDecades, err = db.yearCounts(q); err != nil {
+		return details, err
+	}
+	row = db.d.QueryRow(`SELECT COUNT(*) FROM video WHERE latitude IS NOT NULL`)
+	if err = row.Scan(&details.Geotagged); err != nil {
+		return details, fmt.Errorf("could not get video count: %s", err)
+	}
+	if details.Orientations, err = db.orientationCounts("video"); err != nil {
+		return details, err
+	}
+	if details.Resolutions, err = db.resolutionCounts("video"); err != nil {
+		return details, err
 	}
-	wg.Wait()
-	return nil
+	q = `SELECT video_codec, COUNT(*) FROM video ` +
+		`WHERE video_codec IS NOT NULL ` +
+		`GROUP BY video_codec ` +
+		`ORDER BY video_codec `
+	if details.VideoCodecs, err = db.valueCounts(q); err != nil {
+		return details, err
+	}
+	q = `SELECT audio_codec, COUNT(*) FROM video ` +
+		`WHERE audio_codec IS NOT NULL ` +
+		`GROUP BY audio_codec ` +
+		`ORDER BY audio_codec `
+	if details.AudioCodecs, err = db.valueCounts(q); err != nil {
+		return details, err
+	}
+	q = `SELECT CAST(ROUND(fps) AS INTEGER) AS rate, COUNT(*) FROM video ` +
+		`WHERE fps IS NOT NULL ` +
+		`GROUP BY rate ` +
+		`ORDER BY rate `
+	details.FrameRates, err = db.valueCounts(q)
+	return details, err
 }
 
-func (db DB) PrintAudiosDetails() error {
-	q := `SELECT ` +
-		`strftime('%Y', datetime(created_guess, 'unixepoch', 'localtime')) AS year, ` +
-		`COUNT(*) ` +
-		`FROM file f ` +
-		`WHERE EXISTS (SELECT 1 FROM audio a WHERE a.file = f.id) ` +
-		`GROUP BY year ` +
-		`ORDER BY year `
-	res := make(chan intcount)
-	var wg sync.WaitGroup
-	wg.Go(func() {
-		for i := 0; true; i++ {
-			r, ok := <-res
-			if !ok {
-				return
-			} else if i == 0 {
-				fmt.Println("Audio files can be filtered by the year of file creation:")
-			}
-			f := "\t`-c %d` lists all audio files created in %d (%d files).\n"
-			fmt.Printf(f, r.num, r.num, r.count)
-		}
-	})
-	if err := db.getIntCounts(q, res); err != nil {
-		return err
+func (db DB) AudiosDetails() (AudioDetails, error) {
+	details := AudioDetails{}
+	var err error
+	if details.FileDetails, err = db.fileDetails(audioCondition); err != nil {
+		return details, err
 	}
-	wg.Wait()
-
-	fmt.Printf("Audio files can be filtered by their length:\n")
 	row := db.d.QueryRow(`SELECT COUNT(*) FROM audio WHERE seconds <= 600`)
-	var cnt int
-	if err := row.Scan(&cnt); err != nil {
-		return fmt.Errorf("could not get audio file count: %s", err)
+	if err = row.Scan(&details.Short); err != nil {
+		return details, fmt.Errorf("could not get audio file count: %s", err)
 	}
-	fmt.Printf("\t`-durmax 10m` lists all audio files up to 10 minutes long (%d files).\n", cnt)
 	row = db.d.QueryRow(`SELECT COUNT(*) FROM audio WHERE seconds >= 600`)
-	if err := row.Scan(&cnt); err != nil {
-		return fmt.Errorf("could not get audio file count: %s", err)
+	if err = row.Scan(&details.Long); err != nil {
+		return details, fmt.Errorf("could not get audio file count: %s", err)
 	}
-	fmt.Printf("\t`-durmin 10m` lists all audio files at least 10 minutes long (%d files).\n", cnt)
-
-	q = `SELECT author, COUNT(*) FROM audio ` +
+	q := `SELECT author, COUNT(*) FROM audio ` +
 		`WHERE author IS NOT NULL ` +
 		`GROUP BY author ` +
-		`ORDER BY author ` +
-		`LIMIT 9 `
-	res2 := make(chan strcount)
-	wg.Go(func() {
-		for i := 0; true; i++ {
-			r, ok := <-res2
-			if !ok {
-				return
-			} else if i == 0 {
-				fmt.Println("Audio files can be filtered by their author:")
-			}
-			if i < 8 {
-				f := "\t`-author '%s'` lists all files created by that author (%d files).\n"
-				fmt.Printf(f, r.str, r.count)
-			} else {
-				fmt.Println("\t...")
-			}
-		}
-	})
-	if err := db.getStringCounts(q, res2); err != nil {
-		return err
+		`ORDER BY author `
+	if details.Authors, err = db.valueCounts(q); err != nil {
+		return details, err
 	}
-	wg.Wait()
-
-	q = `SELECT ` +
-		`CAST((year/10)*10 AS TEXT) || '-' || CAST(((year/10)*10+9) AS TEXT) AS decade,` +
-		`COUNT(*) ` +
+	q = `SELECT (year/10)*10 AS decade, COUNT(*) ` +
 		`FROM audio ` +
 		`WHERE year IS NOT NULL ` +
 		`GROUP BY decade ` +
 		`ORDER BY decade `
-	res2 = make(chan strcount)
-	wg.Go(func() {
-		for i := 0; true; i++ {
-			r, ok := <-res2
-			if !ok {
-				return
-			} else if i == 0 {
-				fmt.Println("Audio files can be filtered by the year they were recorded:")
-			}
-			f := "\t`-year %s` lists all files recorded in that decade (%d files).\n"
-			fmt.Printf(f, r.str, r.count)
-		}
-	})
-	if err := db.getStringCounts(q, res2); err != nil {
-		return err
+	if details.RecordedDecades, err = db.yearCounts(q); err != nil {
+		return details, err
+	}
+	q = `SELECT genre, COUNT(*) FROM audio ` +
+		`WHERE genre IS NOT NULL ` +
+		`GROUP BY genre ` +
+		`ORDER BY genre `
+	if details.Genres, err = db.valueCounts(q); err != nil {
+		return details, err
+	}
+	q = `SELECT codec, COUNT(*) FROM audio ` +
+		`WHERE codec IS NOT NULL ` +
+		`GROUP BY codec ` +
+		`ORDER BY codec `
+	if details.Codecs, err = db.valueCounts(q); err != nil {
+		return details, err
 	}
-	wg.Wait()
-	return nil
+	details.Albums, err = db.albumCounts()
+	return details, err
 }
 
-func (db DB) PrintDocumentsDetails() error {
-	q := `SELECT ` +
-		`strftime('%Y', datetime(created_guess, 'unixepoch', 'localtime')) AS year, ` +
-		`COUNT(*) ` +
-		`FROM file f ` +
-		`WHERE EXISTS (SELECT 1 FROM document d WHERE d.file = f.id) ` +
-		`GROUP BY year ` +
-		`ORDER BY year `
-	res := make(chan intcount)
-	var wg sync.WaitGroup
-	wg.Go(func() {
-		for i := 0; true; i++ {
-			r, ok := <-res
-			if !ok {
-				return
-			} else if i == 0 {
-				fmt.Println("Documents can be filtered by the year of creation:")
-			}
-			f := "\t`-c %d` lists all documents created in %d (%d files).\n"
-			fmt.Printf(f, r.num, r.num, r.count)
+func (db DB) albumCounts() ([]AlbumCount, error) {
+	q := `SELECT COALESCE(album_artist, author, '') AS artist, album, COUNT(*) ` +
+		`FROM audio ` +
+		`WHERE album IS NOT NULL ` +
+		`GROUP BY artist, album ` +
+		`ORDER BY artist = '', artist, album `
+	rows, err := db.d.Query(q)
+	if err != nil {
+		return nil, fmt.Errorf("could not query database: %s", err)
+	}
+	defer rows.Close()
+	counts := make([]AlbumCount, 0)
+	for rows.Next() {
+		var c AlbumCount
+		if err := rows.Scan(&c.Artist, &c.Album, &c.Count); err != nil {
+			return nil, fmt.Errorf("could not read from database: %s", err)
 		}
-	})
-	if err := db.getIntCounts(q, res); err != nil {
-		return err
+		counts = append(counts, c)
 	}
-	wg.Wait()
-	return nil
+	return counts, rows.Err()
 }
 
-func (db DB) PrintOthersDetails() error {
-	q := `SELECT ` +
-		`strftime('%Y', datetime(created_guess, 'unixepoch', 'localtime')) AS year, ` +
-		`COUNT(*) ` +
-		`FROM file f ` +
-		`WHERE NOT EXISTS (SELECT 1 FROM picture p WHERE p.file = f.id) ` +
-		`AND NOT EXISTS (SELECT 1 FROM video v WHERE v.file = f.id) ` +
-		`AND NOT EXISTS (SELECT 1 FROM audio a WHERE a.file = f.id) ` +
-		`AND NOT EXISTS (SELECT 1 FROM document d WHERE d.file = f.id) ` +
-		`GROUP BY year ` +
-		`ORDER BY year `
-	res := make(chan intcount)
-	var wg sync.WaitGroup
-	wg.Go(func() {
-		for i := 0; true; i++ {
-			r, ok := <-res
-			if !ok {
-				return
-			} else if i == 0 {
-				fmt.Println("Other files can be filtered by the year of creation:")
-			}
-			f := "\t`-c %d` lists all files created in %d (%d files).\n"
-			fmt.Printf(f, r.num, r.num, r.count)
-		}
-	})
-	if err := db.getIntCounts(q, res); err != nil {
-		return err
+func (db DB) DocumentsDetails() (FileDetails, error) {
+	return db.fileDetails(documentCondition)
+}
+
+func (db DB) ArchivesDetails() (FileDetails, error) {
+	return db.fileDetails(archiveCondition)
+}
+
+func (db DB) ExecutablesDetails() (ExecutableDetails, error) {
+	details := ExecutableDetails{}
+	var err error
+	if details.FileDetails, err = db.fileDetails(executableCondition); err != nil {
+		return details, err
 	}
-	wg.Wait()
-	return nil
+	q := `SELECT arch, COUNT(*) FROM executable ` +
+		`WHERE arch IS NOT NULL ` +
+		`GROUP BY arch ` +
+		`ORDER BY arch `
+	if details.Archs, err = db.valueCounts(q); err != nil {
+		return details, err
+	}
+	q = `SELECT interpreter, COUNT(*) FROM executable ` +
+		`WHERE interpreter IS NOT NULL ` +
+		`GROUP BY interpreter ` +
+		`ORDER BY interpreter `
+	details.Interpreters, err = db.valueCounts(q)
+	return details, err
 }
 
-func (db DB) PrintAllDetails() error {
-	q := `SELECT ` +
-		`strftime('%Y', datetime(created_guess, 'unixepoch', 'localtime')) AS year, ` +
-		`COUNT(*) ` +
-		`FROM file f ` +
-		`GROUP BY year ` +
-		`ORDER BY year `
-	res := make(chan intcount)
-	var wg sync.WaitGroup
-	wg.Go(func() {
-		for i := 0; true; i++ {
-			r, ok := <-res
-			if !ok {
-				return
-			} else if i == 0 {
-				fmt.Println("All files can be filtered by the year of creation:")
-			}
-			f := "\t`-c %d` lists all files created in %d (%d files).\n"
-			fmt.Printf(f, r.num, r.num, r.count)
+func (db DB) OthersDetails() (FileDetails, error) {
+	return db.fileDetails(otherCondition)
+}
+
+func (db DB) AllDetails() (FileDetails, error) {
+	return db.fileDetails(`1 = 1 `)
+}
+
+// orientationCounts counts the files of table, which must be picture or
+// video, per orientation.
+func (db DB) orientationCounts(table string) ([]ValueCount, error) {
+	q := `SELECT CASE ` +
+		`WHEN width > height THEN 'landscape' ` +
+		`WHEN width < height THEN 'portrait' ` +
+		`ELSE 'square' END AS orientation, ` +
+		`COUNT(*) FROM ` + table + ` ` +
+		`WHERE width IS NOT NULL AND height IS NOT NULL ` +
+		`GROUP BY orientation ` +
+		`ORDER BY orientation `
+	return db.valueCounts(q)
+}
+
+// resolutionCounts counts the files of table, which must be picture or
+// video, that have at least one of the commonResolutions. Resolutions
+// no file has are omitted.
+func (db DB) resolutionCounts(table string) ([]ResolutionCount, error) {
+	counts := make([]ResolutionCount, 0)
+	for _, resolution := range commonResolutions {
+		c := ResolutionCount{Resolution: resolution}
+		q := `SELECT COUNT(*) FROM ` + table + ` WHERE MIN(width, height) >= ?`
+		if err := db.d.QueryRow(q, resolution).Scan(&c.Count); err != nil {
+			return nil, fmt.Errorf("could not get %s count: %s", table, err)
+		} else if c.Count == 0 {
+			break
 		}
-	})
-	if err := db.getIntCounts(q, res); err != nil {
-		return err
+		counts = append(counts, c)
 	}
-	wg.Wait()
-	return nil
+	return counts, nil
 }
 
-func (db DB) getIntCounts(query string, out chan intcount) error {
-	defer close(out)
+func (db DB) yearCounts(query string) ([]YearCount, error) {
 	rows, err := db.d.Query(query)
 	if err != nil {
-		return fmt.Errorf("could not query database: %s", err)
+		return nil, fmt.Errorf("could not query database: %s", err)
 	}
+	defer rows.Close()
+	counts := make([]YearCount, 0)
 	for rows.Next() {
-		var num, count int
-		err := rows.Scan(&num, &count)
-		if err != nil {
-			return fmt.Errorf("could not read from database: %s", err)
+		var c YearCount
+		if err := rows.Scan(&c.Year, &c.Count); err != nil {
+			return nil, fmt.Errorf("could not read from database: %s", err)
 		}
-		out <- intcount{num: num, count: count}
+		counts = append(counts, c)
 	}
-	return rows.Err()
+	return counts, rows.Err()
 }
 
-func (db DB) getStringCounts(query string, out chan strcount) error {
-	defer close(out)
-	rows, err := db.d.Query(query)
+func (db DB) valueCounts(query string, args ...any) ([]ValueCount, error) {
+	rows, err := db.d.Query(query, args...)
 	if err != nil {
-		return fmt.Errorf("could not query database: %s", err)
+		return nil, fmt.Errorf("could not query database: %s", err)
 	}
+	defer rows.Close()
+	counts := make([]ValueCount, 0)
 	for rows.Next() {
-		var str string
-		var count int
-		err := rows.Scan(&str, &count)
-		if err != nil {
-			return fmt.Errorf("could not read from database: %s", err)
+		var c ValueCount
+		if err := rows.Scan(&c.Value, &c.Count); err != nil {
+			return nil, fmt.Errorf("could not read from database: %s", err)
 		}
-		out <- strcount{str: str, count: count}
+		counts = append(counts, c)
 	}
-	return rows.Err()
+	return counts, rows.Err()
 }
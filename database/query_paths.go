@@ -3,18 +3,75 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"iter"
+	"regexp"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 type FileFilter struct {
-	CreatedSince, CreatedUntil *time.Time
-	Prefix                     string
+	// CreatedSince and CreatedUntil refer to the time a file was taken,
+	// if it is known, and to its guessed creation time otherwise.
+	CreatedSince, CreatedUntil   *time.Time
+	ModifiedSince, ModifiedUntil *time.Time
+	TakenSince, TakenUntil       *time.Time
+	Prefix                       string
+
+	// Name is a pattern for the name of files. By default, it matches
+	// names containing it, ignoring case. Names are also matched with
+	// glob patterns and regular expressions, as described for
+	// PictureFilter. Except for regular expressions, the search uses
+	// the trigram index of names.
+	Name string
+
+	// MinSize and MaxSize are given in bytes.
+	MinSize, MaxSize *int64
+
+	// InArchive includes members of archives.
+	InArchive bool
+
+	// Only files carrying all of Tags and none of ExcludedTags match.
+	Tags, ExcludedTags []string
+
+	// Sort is one of "modified", "created", "size", "path", "name",
+	// "duration" and "taken". Times, sizes and durations are sorted in
+	// descending order, unless Reverse is true. If Sort is empty, files
+	// are sorted by modification time.
+	Sort    string
+	Reverse bool
+
+	// Random shuffles the files instead of sorting them.
+	Random bool
+
+	// Limit is the maximum number of files. There is no limit, if it
+	// is zero.
+	Limit int
 }
 
+// PictureFilter selects pictures. Its text filters, like those of
+// VideoFilter, AudioFilter and ExecutableFilter, are patterns, that
+// match values equal to them, ignoring case. Patterns starting with ~
+// match values containing the rest, ignoring case. Patterns enclosed in
+// slashes, like /^canon/, are regular expressions in the syntax of Go's
+// regexp package; they ignore case, unless they start with flags like
+// (?-i). Patterns containing *, ? or [ are glob patterns,
+// that are matched ignoring case.
 type PictureFilter struct {
 	FileFilter
 	Camera string
+
+	// Geotagged limits the pictures to those with a known location.
+	Geotagged bool
+	Near      *Area
+
+	// MinResolution is the minimum length of the shorter side in
+	// pixels, e.g. 1080.
+	MinResolution *int
+
+	// Orientation may be empty or one of "portrait", "landscape" and
+	// "square".
+	Orientation string
 }
 
 type VideoFilter struct {
@@ -22,6 +79,29 @@ type VideoFilter struct {
 	MinDuration, MaxDuration *time.Duration
 	Camera                   string
 	MinYear, MaxYear         *int
+
+	// Geotagged limits the videos to those with a known location.
+	Geotagged bool
+	Near      *Area
+
+	// MinResolution is the minimum length of the shorter side in
+	// pixels, e.g. 1080.
+	MinResolution *int
+
+	// Orientation may be empty or one of "portrait", "landscape" and
+	// "square".
+	Orientation string
+
+	// Codec matches the video or the audio codec.
+	Codec string
+
+	MinFrameRate, MaxFrameRate *float64
+}
+
+// Area is a circle on the surface of the earth.
+type Area struct {
+	Latitude, Longitude float64
+	Radius              float64 // In meters.
 }
 
 type AudioFilter struct {
@@ -29,33 +109,70 @@ type AudioFilter struct {
 	MinDuration, MaxDuration *time.Duration
 	Author                   string
 	MinYear, MaxYear         *int
+	Album, Title, Genre      string
+	Codec                    string
+}
+
+type ExecutableFilter struct {
+	FileFilter
+	Arch, Interpreter string
 }
 
 type DocumentFilter struct {
 	FileFilter
 	TxtOnly bool
+
+	// Text is a full-text query in the FTS5 query syntax. If it is
+	// given, documents are sorted by relevance instead of modification
+	// time and Document.Snippet is set.
+	Text string
 }
 
-func (db DB) PrintPicturesPaths(filter PictureFilter) error {
-	q := `SELECT f.path FROM file f ` +
+const fileSelect = `SELECT f.id, f.path, f.size, f.modified, f.created_guess, f.mime, f.taken`
+
+// The conditions that limit the files in f to a category.
+const (
+	pictureCondition    = `EXISTS (SELECT 1 FROM picture p WHERE p.file = f.id) `
+	videoCondition      = `EXISTS (SELECT 1 FROM video v WHERE v.file = f.id) `
+	audioCondition      = `EXISTS (SELECT 1 FROM audio a WHERE a.file = f.id) `
+	documentCondition   = `EXISTS (SELECT 1 FROM document d WHERE d.file = f.id) `
+	archiveCondition    = `EXISTS (SELECT 1 FROM archive r WHERE r.file = f.id) `
+	executableCondition = `EXISTS (SELECT 1 FROM executable x WHERE x.file = f.id) `
+	otherCondition      = `NOT ` + pictureCondition +
+		`AND NOT ` + videoCondition +
+		`AND NOT ` + audioCondition +
+		`AND NOT ` + documentCondition +
+		`AND NOT ` + archiveCondition +
+		`AND NOT ` + executableCondition
+)
+
+// Pictures returns all pictures matching filter, sorted as selected by
+// filter.
+func (db DB) Pictures(filter PictureFilter) iter.Seq2[*Picture, error] {
+	q := fileSelect + `, ` + pictureSelect + ` FROM file f ` +
 		`INNER JOIN picture p ON p.file = f.id ` +
 		`WHERE 1 = 1 ` // Ensure that "AND" can be used to add filters.
 	var args []any
 	q, args = addFileFilters(q, args, filter.FileFilter)
-	if filter.Camera != "" {
-		q += `AND p.camera = ? `
-		args = append(args, filter.Camera)
+	q, args, err := addTextFilter(q, args, filter.Camera, "p.camera")
+	if err != nil {
+		return failed[*Picture](err)
 	}
-	q += `ORDER BY f.modified DESC `
-	rows, err := db.d.Query(q, args...)
+	q, args = addLocationFilters(q, args, "p", filter.Geotagged, filter.Near)
+	q, args, err = addDimensionFilters(q, args, "p", filter.MinResolution, filter.Orientation)
 	if err != nil {
-		return fmt.Errorf("could not query database: %s", err)
+		return failed[*Picture](err)
+	}
+	if q, args, err = addOrder(q, args, filter.FileFilter, ""); err != nil {
+		return failed[*Picture](err)
 	}
-	return printPaths(rows)
+	return query(db, q, args, scanPicture)
 }
 
-func (db DB) PrintVideosPaths(filter VideoFilter) error {
-	q := `SELECT f.path FROM file f ` +
+// Videos returns all videos matching filter, sorted as selected by
+// filter.
+func (db DB) Videos(filter VideoFilter) iter.Seq2[*Video, error] {
+	q := fileSelect + `, ` + videoSelect + ` FROM file f ` +
 		`INNER JOIN video v ON v.file = f.id ` +
 		`WHERE 1 = 1 ` // Ensure that "AND" can be used to add filters.
 	var args []any
@@ -68,9 +185,9 @@ func (db DB) PrintVideosPaths(filter VideoFilter) error {
 		q += `AND v.seconds <= ? `
 		args = append(args, int(filter.MaxDuration.Seconds()))
 	}
-	if filter.Camera != "" {
-		q += `AND v.camera = ? `
-		args = append(args, filter.Camera)
+	q, args, err := addTextFilter(q, args, filter.Camera, "v.camera")
+	if err != nil {
+		return failed[*Video](err)
 	}
 	if filter.MinYear != nil {
 		q += `AND v.year >= ? `
@@ -80,16 +197,33 @@ func (db DB) PrintVideosPaths(filter VideoFilter) error {
 		q += `AND v.year <= ? `
 		args = append(args, *filter.MaxYear)
 	}
-	q += `ORDER BY f.modified DESC `
-	rows, err := db.d.Query(q, args...)
+	q, args = addLocationFilters(q, args, "v", filter.Geotagged, filter.Near)
+	q, args, err = addDimensionFilters(q, args, "v", filter.MinResolution, filter.Orientation)
+	if err != nil {
+		return failed[*Video](err)
+	}
+	q, args, err = addTextFilter(q, args, filter.Codec, "v.video_codec", "v.audio_codec")
 	if err != nil {
-		return fmt.Errorf("could not query database: %s", err)
+		return failed[*Video](err)
+	}
+	if filter.MinFrameRate != nil {
+		q += `AND v.fps >= ? `
+		args = append(args, *filter.MinFrameRate)
+	}
+	if filter.MaxFrameRate != nil {
+		q += `AND v.fps <= ? `
+		args = append(args, *filter.MaxFrameRate)
 	}
-	return printPaths(rows)
+	if q, args, err = addOrder(q, args, filter.FileFilter, "v.seconds"); err != nil {
+		return failed[*Video](err)
+	}
+	return query(db, q, args, scanVideo)
 }
 
-func (db DB) PrintAudiosPaths(filter AudioFilter) error {
-	q := `SELECT f.path FROM file f ` +
+// Audios returns all audio files matching filter, sorted as selected
+// by filter.
+func (db DB) Audios(filter AudioFilter) iter.Seq2[*Audio, error] {
+	q := fileSelect + `, ` + audioSelect + ` FROM file f ` +
 		`INNER JOIN audio a ON a.file = f.id ` +
 		`WHERE 1 = 1 ` // Ensure that "AND" can be used to add filters.
 	var args []any
@@ -102,10 +236,6 @@ func (db DB) PrintAudiosPaths(filter AudioFilter) error {
 		q += `AND a.seconds <= ? `
 		args = append(args, int(filter.MaxDuration.Seconds()))
 	}
-	if filter.Author != "" {
-		q += `AND a.author = ? `
-		args = append(args, filter.Author)
-	}
 	if filter.MinYear != nil {
 		q += `AND a.year >= ? `
 		args = append(args, *filter.MinYear)
@@ -114,69 +244,302 @@ func (db DB) PrintAudiosPaths(filter AudioFilter) error {
 		q += `AND a.year <= ? `
 		args = append(args, *filter.MaxYear)
 	}
-	q += `ORDER BY f.modified DESC `
-	rows, err := db.d.Query(q, args...)
-	if err != nil {
-		return fmt.Errorf("could not query database: %s", err)
+	var err error
+	for _, f := range []struct{ pattern, expr string }{
+		{filter.Author, "a.author"},
+		{filter.Album, "a.album"},
+		{filter.Title, "a.title"},
+		{filter.Genre, "a.genre"},
+		{filter.Codec, "a.codec"},
+	} {
+		if q, args, err = addTextFilter(q, args, f.pattern, f.expr); err != nil {
+			return failed[*Audio](err)
+		}
+	}
+	if q, args, err = addOrder(q, args, filter.FileFilter, "a.seconds"); err != nil {
+		return failed[*Audio](err)
 	}
-	return printPaths(rows)
+	return query(db, q, args, scanAudio)
 }
 
-func (db DB) PrintDocumentsPaths(filter DocumentFilter) error {
-	q := `SELECT f.path FROM file f ` +
-		`INNER JOIN document d ON d.file = f.id ` +
-		`WHERE 1 = 1 ` // Ensure that "AND" can be used to add filters.
+// Documents returns all documents matching filter, sorted as selected
+// by filter. If a full-text search is done without selecting a sort
+// key, the best matches come first instead. The text of the documents
+// is not loaded.
+func (db DB) Documents(filter DocumentFilter) iter.Seq2[*Document, error] {
 	var args []any
+	q := fileSelect + `, NULL FROM file f ` +
+		`INNER JOIN document d ON d.file = f.id `
+	if filter.Text != "" {
+		q = fileSelect + `, t.snippet FROM file f ` +
+			`INNER JOIN document d ON d.file = f.id ` +
+			`INNER JOIN (` +
+			`SELECT rowid, rank, snippet(document_text, 0, '[', ']', '…', 12) AS snippet ` +
+			`FROM document_text WHERE document_text MATCH ?` +
+			`) t ON t.rowid = f.id `
+		args = append(args, filter.Text)
+	}
+	q += `WHERE 1 = 1 ` // Ensure that "AND" can be used to add filters.
 	q, args = addFileFilters(q, args, filter.FileFilter)
 	if filter.TxtOnly {
 		q += `AND f.mime LIKE ? `
 		args = append(args, "text/%")
 	}
-	q += `ORDER BY f.modified DESC `
-	rows, err := db.d.Query(q, args...)
+	var err error
+	if filter.Text != "" && filter.Sort == "" && !filter.Random {
+		q += `ORDER BY t.rank `
+		if filter.Reverse {
+			q += `DESC `
+		}
+		q, args = addLimit(q, args, filter.FileFilter)
+	} else if q, args, err = addOrder(q, args, filter.FileFilter, ""); err != nil {
+		return failed[*Document](err)
+	}
+	return query(db, q, args, scanDocument)
+}
+
+// Archives returns all archives matching filter, sorted as selected by
+// filter.
+// The members of the archives are not loaded.
+func (db DB) Archives(filter FileFilter) iter.Seq2[*Archive, error] {
+	q := fileSelect + ` FROM file f ` +
+		`INNER JOIN archive r ON r.file = f.id ` +
+		`WHERE 1 = 1 ` // Ensure that "AND" can be used to add filters.
+	var args []any
+	q, args = addFileFilters(q, args, filter)
+	q, args, err := addOrder(q, args, filter, "")
+	if err != nil {
+		return failed[*Archive](err)
+	}
+	return query(db, q, args, scanArchive)
+}
+
+// Executables returns all executables matching filter, sorted as
+// selected by filter.
+func (db DB) Executables(filter ExecutableFilter) iter.Seq2[*Executable, error] {
+	q := fileSelect + `, x.arch, x.linking, x.interpreter FROM file f ` +
+		`INNER JOIN executable x ON x.file = f.id ` +
+		`WHERE 1 = 1 ` // Ensure that "AND" can be used to add filters.
+	var args []any
+	q, args = addFileFilters(q, args, filter.FileFilter)
+	q, args, err := addTextFilter(q, args, filter.Arch, "x.arch")
 	if err != nil {
-		return fmt.Errorf("could not query database: %s", err)
+		return failed[*Executable](err)
 	}
-	return printPaths(rows)
+	if q, args, err = addTextFilter(q, args, filter.Interpreter, "x.interpreter"); err != nil {
+		return failed[*Executable](err)
+	}
+	if q, args, err = addOrder(q, args, filter.FileFilter, ""); err != nil {
+		return failed[*Executable](err)
+	}
+	return query(db, q, args, scanExecutable)
 }
 
-func (db DB) PrintOthersPaths(filter FileFilter) error {
-	q := `SELECT path FROM file f ` +
-		`WHERE NOT EXISTS (SELECT 1 FROM picture p WHERE p.file = f.id) ` +
-		`AND NOT EXISTS (SELECT 1 FROM video v WHERE v.file = f.id) ` +
-		`AND NOT EXISTS (SELECT 1 FROM audio a WHERE a.file = f.id) ` +
-		`AND NOT EXISTS (SELECT 1 FROM document d WHERE d.file = f.id) `
+// Others returns all files that fit no other category, sorted as
+// selected by filter.
+func (db DB) Others(filter FileFilter) iter.Seq2[*File, error] {
+	q := fileSelect + ` FROM file f WHERE ` + otherCondition
 	var args []any
 	q, args = addFileFilters(q, args, filter)
-	q += `ORDER BY f.modified DESC `
-	rows, err := db.d.Query(q, args...)
+	q, args, err := addOrder(q, args, filter, "")
 	if err != nil {
-		return fmt.Errorf("could not query database: %s", err)
+		return failed[*File](err)
 	}
-	return printPaths(rows)
+	return query(db, q, args, scanFile)
 }
 
-func (db DB) PrintAllPaths(filter FileFilter) error {
-	q := `SELECT path FROM file f WHERE 1 = 1 `
+// allSelect selects the files together with the columns of all
+// categories, as scanned by scanEntry.
+const allSelect = fileSelect + `, ` +
+	`p.file IS NOT NULL, ` + pictureSelect + `, ` +
+	`v.file IS NOT NULL, ` + videoSelect + `, ` +
+	`a.file IS NOT NULL, ` + audioSelect + `, ` +
+	`d.file IS NOT NULL, ` +
+	`r.file IS NOT NULL, ` +
+	`x.file IS NOT NULL, x.arch, x.linking, x.interpreter ` +
+	`FROM file f ` +
+	`LEFT JOIN picture p ON p.file = f.id ` +
+	`LEFT JOIN video v ON v.file = f.id ` +
+	`LEFT JOIN audio a ON a.file = f.id ` +
+	`LEFT JOIN document d ON d.file = f.id ` +
+	`LEFT JOIN archive r ON r.file = f.id ` +
+	`LEFT JOIN executable x ON x.file = f.id `
+
+// All returns all files matching filter, regardless of their category,
+// sorted as selected by filter. The entries are of the type matching
+// their category, e.g. *Picture for pictures and *File for other files.
+func (db DB) All(filter FileFilter) iter.Seq2[Entry, error] {
+	q := allSelect + `WHERE 1 = 1 `
 	var args []any
 	q, args = addFileFilters(q, args, filter)
-	q += `ORDER BY f.modified DESC `
-	rows, err := db.d.Query(q, args...)
+	q, args, err := addOrder(q, args, filter, "COALESCE(v.seconds, a.seconds)")
 	if err != nil {
-		return fmt.Errorf("could not query database: %s", err)
+		return failed[Entry](err)
+	}
+	return query(db, q, args, scanEntry)
+}
+
+// sortKeys maps the values of FileFilter.Sort to the sorted expressions
+// and whether they are sorted in descending order.
+var sortKeys = map[string]struct {
+	expr       string
+	descending bool
+}{
+	"":         {`f.modified`, true},
+	"modified": {`f.modified`, true},
+	"created":  {`COALESCE(f.taken, f.created_guess)`, true},
+	"size":     {`f.size`, true},
+	"path":     {`f.path`, false},
+	"name":     {`f.name`, false},
+	"taken":    {`f.taken`, true},
+}
+
+// addOrder adds the ORDER BY and LIMIT clauses selected by filter to q.
+// duration is the expression of the duration of the queried files; it
+// is empty, if they have none.
+func addOrder(q string, args []any, filter FileFilter, duration string) (string, []any, error) {
+	key, ok := sortKeys[filter.Sort]
+	if filter.Sort == "duration" && duration != "" {
+		key.expr, key.descending, ok = duration, true, true
+	}
+	switch {
+	case filter.Random:
+		q += `ORDER BY random() `
+	case !ok:
+		return q, args, fmt.Errorf("cannot sort by '%s'", filter.Sort)
+	case key.descending != filter.Reverse:
+		q += `ORDER BY ` + key.expr + ` DESC `
+	default:
+		q += `ORDER BY ` + key.expr + ` `
+	}
+	q, args = addLimit(q, args, filter)
+	return q, args, nil
+}
+
+func addLimit(q string, args []any, filter FileFilter) (string, []any) {
+	if filter.Limit > 0 {
+		q += `LIMIT ? `
+		args = append(args, filter.Limit)
+	}
+	return q, args
+}
+
+// addDimensionFilters adds the filters for the width and height columns
+// of the table with the given alias to q.
+func addDimensionFilters(q string, args []any, alias string, minResolution *int, orientation string) (string, []any, error) {
+	if minResolution != nil {
+		q += `AND MIN(` + alias + `.width, ` + alias + `.height) >= ? `
+		args = append(args, *minResolution)
+	}
+	switch orientation {
+	case "":
+	case "portrait":
+		q += `AND ` + alias + `.height > ` + alias + `.width `
+	case "landscape":
+		q += `AND ` + alias + `.width > ` + alias + `.height `
+	case "square":
+		q += `AND ` + alias + `.width = ` + alias + `.height `
+	default:
+		return q, args, fmt.Errorf("unknown orientation '%s'", orientation)
+	}
+	return q, args, nil
+}
+
+// addTextFilter adds a filter to q, that matches if any of exprs
+// matches pattern as described for PictureFilter. An empty pattern
+// matches everything.
+func addTextFilter(q string, args []any, pattern string, exprs ...string) (string, []any, error) {
+	if pattern == "" {
+		return q, args, nil
+	}
+	conds := make([]string, len(exprs))
+	for i, expr := range exprs {
+		cond, arg, err := textCondition(expr, pattern)
+		if err != nil {
+			return q, args, err
+		}
+		conds[i] = cond
+		args = append(args, arg)
+	}
+	q += `AND (` + strings.Join(conds, ` OR `) + `) `
+	return q, args, nil
+}
+
+// textCondition returns a condition, that is true if expr matches
+// pattern as described for PictureFilter, and its argument.
+func textCondition(expr, pattern string) (string, any, error) {
+	if re, ok := RegexpPattern(pattern); ok {
+		if _, err := regexp.Compile(re); err != nil {
+			return "", nil, fmt.Errorf("invalid regular expression '%s': %s", re, err)
+		}
+		return expr + ` REGEXP ?`, re, nil
+	} else if substring, ok := strings.CutPrefix(pattern, "~"); ok {
+		return `instr(casefold(` + expr + `), ?) > 0`, casefold(substring), nil
+	} else if IsGlobPattern(pattern) {
+		return `casefold(` + expr + `) GLOB ?`, casefold(pattern), nil
+	}
+	return `casefold(` + expr + `) = ?`, casefold(pattern), nil
+}
+
+// RegexpPattern returns the regular expression of a text pattern like
+// /^canon/ and whether pattern is one. The regular expression ignores
+// case, unless the pattern starts with flags like (?s) or (?-i).
+func RegexpPattern(pattern string) (string, bool) {
+	if len(pattern) < 2 || pattern[0] != '/' || pattern[len(pattern)-1] != '/' {
+		return "", false
 	}
-	return printPaths(rows)
+	re := pattern[1 : len(pattern)-1]
+	if !regexpFlags.MatchString(re) {
+		re = `(?i)` + re
+	}
+	return re, true
+}
+
+// regexpFlags matches regular expressions starting with flags.
+var regexpFlags = regexp.MustCompile(`^\(\?[imsU-]+[:)]`)
+
+// IsGlobPattern reports whether pattern contains any of the special
+// characters of glob patterns, i.e. *, ? and [.
+func IsGlobPattern(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
 }
 
 func addFileFilters(q string, args []any, filter FileFilter) (string, []any) {
+	if !filter.InArchive {
+		q += `AND f.archive IS NULL `
+	}
 	if filter.CreatedSince != nil {
-		q += `AND f.created_guess >= ? `
+		q += `AND COALESCE(f.taken, f.created_guess) >= ? `
 		args = append(args, filter.CreatedSince.Unix())
 	}
 	if filter.CreatedUntil != nil {
-		q += `AND f.created_guess <= ? `
+		q += `AND COALESCE(f.taken, f.created_guess) <= ? `
 		args = append(args, filter.CreatedUntil.Unix())
 	}
+	if filter.ModifiedSince != nil {
+		q += `AND f.modified >= ? `
+		args = append(args, filter.ModifiedSince.Unix())
+	}
+	if filter.ModifiedUntil != nil {
+		q += `AND f.modified <= ? `
+		args = append(args, filter.ModifiedUntil.Unix())
+	}
+	if filter.TakenSince != nil {
+		q += `AND f.taken >= ? `
+		args = append(args, filter.TakenSince.Unix())
+	}
+	if filter.TakenUntil != nil {
+		q += `AND f.taken <= ? `
+		args = append(args, filter.TakenUntil.Unix())
+	}
+	if filter.MinSize != nil {
+		q += `AND f.size >= ? `
+		args = append(args, *filter.MinSize)
+	}
+	if filter.MaxSize != nil {
+		q += `AND f.size <= ? `
+		args = append(args, *filter.MaxSize)
+	}
 	if filter.Prefix != "" {
 		q += `AND f.path LIKE ? `
 		prefix := strings.ReplaceAll(filter.Prefix, `\`, `\\`)
@@ -184,17 +547,336 @@ func addFileFilters(q string, args []any, filter FileFilter) (string, []any) {
 		prefix = strings.ReplaceAll(prefix, `_`, `\_`)
 		args = append(args, prefix+`%`)
 	}
+	if filter.Name != "" {
+		cond, nameArgs := nameCondition(filter.Name)
+		q += `AND ` + cond + ` `
+		args = append(args, nameArgs...)
+	}
+	for _, tag := range filter.Tags {
+		q += `AND EXISTS (SELECT 1 FROM tag t WHERE t.path = f.path AND t.tag = ?) `
+		args = append(args, tag)
+	}
+	for _, tag := range filter.ExcludedTags {
+		q += `AND NOT EXISTS (SELECT 1 FROM tag t WHERE t.path = f.path AND t.tag = ?) `
+		args = append(args, tag)
+	}
 	return q, args
 }
 
-func printPaths(rows *sql.Rows) error {
-	for rows.Next() {
-		p := new(string)
-		err := rows.Scan(&p)
+// nameCondition returns the condition matching the names of files with
+// pattern, as described for FileFilter.Name, and its arguments. The
+// trigram index finds the names containing all literal parts of
+// pattern, that are at least three characters long, before the whole
+// pattern is matched.
+func nameCondition(pattern string) (string, []any) {
+	if re, ok := RegexpPattern(pattern); ok {
+		return `f.name REGEXP ?`, []any{re}
+	}
+	pattern = strings.TrimPrefix(pattern, "~")
+	literals := []string{pattern}
+	cond := `instr(casefold(f.name), ?) > 0`
+	if IsGlobPattern(pattern) {
+		literals = globLiterals(pattern)
+		cond = `casefold(f.name) GLOB ?`
+	}
+	var phrases []string
+	for _, literal := range literals {
+		if utf8.RuneCountInString(literal) >= 3 {
+			phrases = append(phrases, `"`+strings.ReplaceAll(literal, `"`, `""`)+`"`)
+		}
+	}
+	if len(phrases) == 0 {
+		return cond, []any{casefold(pattern)}
+	}
+	cond = `f.id IN (SELECT rowid FROM file_name_text WHERE file_name_text MATCH ?) AND ` + cond
+	return `(` + cond + `)`, []any{strings.Join(phrases, ` AND `), casefold(pattern)}
+}
+
+// globLiterals returns the parts of the glob pattern, that are matched
+// literally, i.e. the parts between wildcards and character classes.
+func globLiterals(pattern string) []string {
+	var literals []string
+	var literal strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '*', '?':
+		case '[':
+			// A ] right after [ or [^ is part of the class.
+			end := i + 1
+			if end < len(pattern) && pattern[end] == '^' {
+				end++
+			}
+			if end < len(pattern) && pattern[end] == ']' {
+				end++
+			}
+			for end < len(pattern) && pattern[end] != ']' {
+				end++
+			}
+			i = end
+		default:
+			literal.WriteByte(pattern[i])
+			continue
+		}
+		literals = append(literals, literal.String())
+		literal.Reset()
+	}
+	return append(literals, literal.String())
+}
+
+// failed returns an iterator that only yields err.
+func failed[T any](err error) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		yield(zero, err)
+	}
+}
+
+// query runs q and yields the rows converted by scan. The query is only
+// run once iteration starts.
+func query[T any](db DB, q string, args []any, scan func(*sql.Rows) (T, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		rows, err := db.d.Query(q, args...)
 		if err != nil {
-			return fmt.Errorf("could not read from database: %s", err)
+			yield(zero, fmt.Errorf("could not query database: %s", err))
+			return
+		}
+		defer rows.Close()
+		for rows.Next() {
+			entry, err := scan(rows)
+			if err != nil {
+				yield(zero, fmt.Errorf("could not read from database: %s", err))
+				return
+			}
+			if !yield(entry, nil) {
+				return
+			}
 		}
-		fmt.Println(*p)
+		if err = rows.Err(); err != nil {
+			yield(zero, fmt.Errorf("could not read from database: %s", err))
+		}
+	}
+}
+
+// fileRow receives the columns selected by fileSelect.
+type fileRow struct {
+	f                 File
+	modified, created int64
+	taken             *int64
+}
+
+func (r *fileRow) dest() []any {
+	return []any{&r.f.id, &r.f.Path, &r.f.Size, &r.modified, &r.created, &r.f.MIME, &r.taken}
+}
+
+func (r *fileRow) file() *File {
+	r.f.Modified = time.Unix(r.modified, 0)
+	r.f.CreatedGuess = time.Unix(r.created, 0)
+	if r.taken != nil {
+		taken := time.Unix(*r.taken, 0)
+		r.f.Taken = &taken
+	}
+	return &r.f
+}
+
+func scanFile(rows *sql.Rows) (*File, error) {
+	var r fileRow
+	err := rows.Scan(r.dest()...)
+	return r.file(), err
+}
+
+// pictureSelect selects the columns of picture, that are received by
+// pictureRow.
+const pictureSelect = `p.camera, p.latitude, p.longitude, p.altitude, p.width, p.height, p.phash`
+
+type pictureRow struct {
+	camera                        sql.NullString
+	latitude, longitude, altitude *float64
+	width, height                 *int
+	phash                         *int64
+}
+
+func (r *pictureRow) dest() []any {
+	return []any{&r.camera, &r.latitude, &r.longitude, &r.altitude, &r.width, &r.height, &r.phash}
+}
+
+func (r *pictureRow) picture(f *File) *Picture {
+	pic := &Picture{
+		File:     f,
+		Camera:   r.camera.String,
+		Location: toLocation(r.latitude, r.longitude, r.altitude),
+		Width:    r.width,
+		Height:   r.height,
+	}
+	if r.phash != nil {
+		h := uint64(*r.phash)
+		pic.PerceptualHash = &h
+	}
+	return pic
+}
+
+func scanPicture(rows *sql.Rows) (*Picture, error) {
+	var r fileRow
+	var p pictureRow
+	err := rows.Scan(append(r.dest(), p.dest()...)...)
+	return p.picture(r.file()), err
+}
+
+// videoSelect selects the columns of video, that are received by
+// videoRow.
+const videoSelect = `v.seconds, v.camera, v.year, v.latitude, v.longitude, v.altitude, ` +
+	`v.width, v.height, v.fps, v.video_codec, v.audio_codec, v.bitrate`
+
+type videoRow struct {
+	seconds                       *int64
+	camera                        sql.NullString
+	year                          *int
+	latitude, longitude, altitude *float64
+	width, height                 *int
+	fps                           *float64
+	videoCodec, audioCodec        sql.NullString
+	bitrate                       *int
+}
+
+func (r *videoRow) dest() []any {
+	return []any{&r.seconds, &r.camera, &r.year, &r.latitude, &r.longitude, &r.altitude,
+		&r.width, &r.height, &r.fps, &r.videoCodec, &r.audioCodec, &r.bitrate}
+}
+
+func (r *videoRow) video(f *File) *Video {
+	return &Video{
+		File:       f,
+		Duration:   toDuration(r.seconds),
+		Camera:     r.camera.String,
+		Year:       r.year,
+		Location:   toLocation(r.latitude, r.longitude, r.altitude),
+		Width:      r.width,
+		Height:     r.height,
+		FrameRate:  r.fps,
+		VideoCodec: r.videoCodec.String,
+		AudioCodec: r.audioCodec.String,
+		Bitrate:    r.bitrate,
+	}
+}
+
+func scanVideo(rows *sql.Rows) (*Video, error) {
+	var r fileRow
+	var v videoRow
+	err := rows.Scan(append(r.dest(), v.dest()...)...)
+	return v.video(r.file()), err
+}
+
+// audioSelect selects the columns of audio, that are received by
+// audioRow.
+const audioSelect = `a.seconds, a.author, a.year, a.album_artist, a.album, a.title, a.genre, a.track, a.disc, ` +
+	`a.codec, a.bitrate`
+
+type audioRow struct {
+	seconds                                  *int64
+	author, albumArtist, album, title, genre sql.NullString
+	year, track, disc                        *int
+	codec                                    sql.NullString
+	bitrate                                  *int
+}
+
+func (r *audioRow) dest() []any {
+	return []any{&r.seconds, &r.author, &r.year,
+		&r.albumArtist, &r.album, &r.title, &r.genre, &r.track, &r.disc,
+		&r.codec, &r.bitrate}
+}
+
+func (r *audioRow) audio(f *File) *Audio {
+	return &Audio{
+		File:        f,
+		Duration:    toDuration(r.seconds),
+		Author:      r.author.String,
+		Year:        r.year,
+		AlbumArtist: r.albumArtist.String,
+		Album:       r.album.String,
+		Title:       r.title.String,
+		Genre:       r.genre.String,
+		Track:       r.track,
+		Disc:        r.disc,
+		Codec:       r.codec.String,
+		Bitrate:     r.bitrate,
+	}
+}
+
+func scanAudio(rows *sql.Rows) (*Audio, error) {
+	var r fileRow
+	var a audioRow
+	err := rows.Scan(append(r.dest(), a.dest()...)...)
+	return a.audio(r.file()), err
+}
+
+func scanDocument(rows *sql.Rows) (*Document, error) {
+	var r fileRow
+	var snippet sql.NullString
+	err := rows.Scan(append(r.dest(), &snippet)...)
+	return &Document{File: r.file(), Snippet: snippet.String}, err
+}
+
+func scanArchive(rows *sql.Rows) (*Archive, error) {
+	f, err := scanFile(rows)
+	return &Archive{File: f}, err
+}
+
+func scanExecutable(rows *sql.Rows) (*Executable, error) {
+	var r fileRow
+	var arch, linking, interpreter sql.NullString
+	err := rows.Scan(append(r.dest(), &arch, &linking, &interpreter)...)
+	return &Executable{
+		File:        r.file(),
+		Arch:        arch.String,
+		Linking:     linking.String,
+		Interpreter: interpreter.String,
+	}, err
+}
+
+// scanEntry scans the rows of the query used by DB.All.
+func scanEntry(rows *sql.Rows) (Entry, error) {
+	var r fileRow
+	var isPic, isVid, isAudio, isDoc, isArchive, isExe bool
+	var arch, linking, interpreter sql.NullString
+	var p pictureRow
+	var v videoRow
+	var a audioRow
+	dest := append(r.dest(), &isPic)
+	dest = append(dest, p.dest()...)
+	dest = append(dest, &isVid)
+	dest = append(dest, v.dest()...)
+	dest = append(dest, &isAudio)
+	dest = append(dest, a.dest()...)
+	err := rows.Scan(append(dest,
+		&isDoc, &isArchive,
+		&isExe, &arch, &linking, &interpreter,
+	)...)
+	switch {
+	case isPic:
+		return p.picture(r.file()), err
+	case isVid:
+		return v.video(r.file()), err
+	case isAudio:
+		return a.audio(r.file()), err
+	case isDoc:
+		return &Document{File: r.file()}, err
+	case isArchive:
+		return &Archive{File: r.file()}, err
+	case isExe:
+		return &Executable{
+			File:        r.file(),
+			Arch:        arch.String,
+			Linking:     linking.String,
+			Interpreter: interpreter.String,
+		}, err
+	}
+	return r.file(), err
+}
+
+func toDuration(seconds *int64) *time.Duration {
+	if seconds == nil {
+		return nil
 	}
-	return rows.Err()
+	d := time.Duration(*seconds) * time.Second
+	return &d
 }
@@ -10,31 +10,50 @@ import (
 type ShortFileInfo struct {
 	Size    int64
 	ModTime time.Time
+
+	// Reindex is true if the file was marked for reindexing by a
+	// schema update.
+	Reindex bool
 }
 
 func (db DB) AllFileCount() (int, error) {
-	row := db.d.QueryRow(`SELECT COUNT(*) FROM file`)
+	row := db.d.QueryRow(`SELECT COUNT(*) FROM file WHERE archive IS NULL`)
+	var cnt int
+	return cnt, row.Scan(&cnt)
+}
+
+// FileCountAt returns the number of files below the given path.
+// Members of archives are not counted.
+func (db DB) FileCountAt(path string) (int, error) {
+	lower, upper := below(path)
+	row := db.d.QueryRow(`SELECT COUNT(*) FROM file WHERE path > ? AND path < ? AND archive IS NULL`,
+		lower, upper)
 	var cnt int
 	return cnt, row.Scan(&cnt)
 }
 
 // AllFileInfosAt returns a map with paths as keys, containing all
-// stored paths below the given path with file info.
+// stored paths below the given path with file info. Members of archives
+// are omitted.
 //
 // db.Begin must have been called to create a transaction before calling
 // AllFileInfosAt.
 func (db DB) AllFileInfosAt(path string) (map[string]ShortFileInfo, error) {
-	q := `SELECT path, size, modified FROM file WHERE path LIKE ?`
-	rows, err := db.tx.Query(q, filepath.Join(path, "%"))
+	q := `SELECT f.path, f.size, f.modified, r.file IS NOT NULL FROM file f ` +
+		`LEFT JOIN reindex r ON r.file = f.id ` +
+		`WHERE f.path > ? AND f.path < ? AND f.archive IS NULL`
+	lower, upper := below(path)
+	rows, err := db.tx.Query(q, lower, upper)
 	if err != nil {
 		return nil, fmt.Errorf("could not query database: %s", err)
 	}
+	defer rows.Close()
 	infos := make(map[string]ShortFileInfo)
 	for rows.Next() {
 		var path string
 		var unixModTime int64
 		info := ShortFileInfo{}
-		if err = rows.Scan(&path, &info.Size, &unixModTime); err != nil {
+		if err = rows.Scan(&path, &info.Size, &unixModTime, &info.Reindex); err != nil {
 			return nil, fmt.Errorf("could not read from database: %s", err)
 		}
 		info.ModTime = time.Unix(unixModTime, 0)
@@ -67,3 +86,29 @@ func (db DB) DeletePaths(paths []string) error {
 	}
 	return nil
 }
+
+// DeleteTree deletes the file entry for path and all file entries below
+// path. It returns the number of deleted entries.
+//
+// db.Begin must have been called to create a transaction before calling
+// DeleteTree.
+func (db DB) DeleteTree(path string) (int, error) {
+	q := `DELETE FROM file WHERE path = ? OR (path > ? AND path < ?)`
+	lower, upper := below(path)
+	res, err := db.tx.Exec(q, path, lower, upper)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// below returns the bounds of the paths below the directory path. Unlike
+// LIKE patterns, comparing with the bounds is case-sensitive and has no
+// wildcards.
+func below(path string) (lower, upper string) {
+	// All paths below path lie between path + separator and path +
+	// (separator + 1):
+	dir := strings.TrimSuffix(path, string(filepath.Separator))
+	return dir + string(filepath.Separator), dir + string(filepath.Separator+1)
+}
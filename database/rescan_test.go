@@ -0,0 +1,86 @@
+package database
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestFilesAtMatchPathsExactly(t *testing.T) {
+	db := newTestDB(t)
+	addFiles(t, db,
+		"/t/Raw/a.jpg",
+		"/t/raw/b.jpg",
+		"/t/Raw.jpg",
+		"/t/Raw2/c.jpg",
+		"/t/a_b/d.txt",
+		"/t/axb/e.txt",
+		"/t/a%b/f.txt",
+	)
+	all := []string{"/t/Raw.jpg", "/t/Raw/a.jpg", "/t/Raw2/c.jpg", "/t/a%b/f.txt",
+		"/t/a_b/d.txt", "/t/axb/e.txt", "/t/raw/b.jpg"}
+	tests := []struct {
+		dir  string
+		want []string
+	}{
+		{"/t/Raw", []string{"/t/Raw/a.jpg"}},
+		{"/t/raw", []string{"/t/raw/b.jpg"}},
+		{"/t/RAW", nil},
+		{"/t/a_b", []string{"/t/a_b/d.txt"}},
+		{"/t/axb", []string{"/t/axb/e.txt"}},
+		{"/t/a%b", []string{"/t/a%b/f.txt"}},
+		{"/t/a", nil},
+		{"/t", all},
+		{"/t/", all},
+		{"/", all},
+	}
+	for _, test := range tests {
+		if err := db.BeginTx(); err != nil {
+			t.Fatal(err)
+		}
+		infos, err := db.AllFileInfosAt(test.dir)
+		if rollbackErr := db.Rollback(); err == nil {
+			err = rollbackErr
+		}
+		if err != nil {
+			t.Fatalf("AllFileInfosAt(%q): %s", test.dir, err)
+		}
+		got := slices.Sorted(maps.Keys(infos))
+		if !slices.Equal(got, test.want) {
+			t.Errorf("AllFileInfosAt(%q) = %q, want %q", test.dir, got, test.want)
+		}
+		count, err := db.FileCountAt(test.dir)
+		if err != nil {
+			t.Fatalf("FileCountAt(%q): %s", test.dir, err)
+		}
+		if count != len(test.want) {
+			t.Errorf("FileCountAt(%q) = %d, want %d", test.dir, count, len(test.want))
+		}
+	}
+}
+
+func TestDeleteTreeKeepsSiblings(t *testing.T) {
+	db := newTestDB(t)
+	addFiles(t, db, "/t/Raw/a.jpg", "/t/raw/b.jpg", "/t/a_b/c.txt", "/t/axb/d.txt")
+	if err := db.BeginTx(); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{"/t/Raw", "/t/a_b"} {
+		if n, err := db.DeleteTree(dir); err != nil {
+			t.Fatal(err)
+		} else if n != 1 {
+			t.Errorf("DeleteTree(%q) deleted %d files, want 1", dir, n)
+		}
+	}
+	infos, err := db.AllFileInfosAt("/t")
+	if err := db.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"/t/axb/d.txt", "/t/raw/b.jpg"}
+	if got := slices.Sorted(maps.Keys(infos)); !slices.Equal(got, want) {
+		t.Errorf("files after DeleteTree = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,26 @@
+package database
+
+// Pictures, videos and audio files are reindexed to gather their
+// resolution, frame rate, codecs and bitrate.
+const schemaV10 = `
+ALTER TABLE picture ADD COLUMN width INTEGER;
+ALTER TABLE picture ADD COLUMN height INTEGER;
+
+ALTER TABLE video ADD COLUMN width INTEGER;
+ALTER TABLE video ADD COLUMN height INTEGER;
+ALTER TABLE video ADD COLUMN fps REAL;
+ALTER TABLE video ADD COLUMN video_codec TEXT;
+ALTER TABLE video ADD COLUMN audio_codec TEXT;
+ALTER TABLE video ADD COLUMN bitrate INTEGER;
+CREATE INDEX video_video_codec ON video(video_codec);
+
+ALTER TABLE audio ADD COLUMN codec TEXT;
+ALTER TABLE audio ADD COLUMN bitrate INTEGER;
+CREATE INDEX audio_codec ON audio(codec);
+
+INSERT OR IGNORE INTO reindex (file)
+SELECT COALESCE(archive, id) FROM file
+WHERE id IN (SELECT file FROM picture UNION SELECT file FROM video UNION SELECT file FROM audio);
+
+PRAGMA user_version = 10;
+`
@@ -0,0 +1,11 @@
+package database
+
+// Pictures are reindexed to compute their perceptual hash.
+const schemaV11 = `
+ALTER TABLE picture ADD COLUMN phash INTEGER;
+
+INSERT OR IGNORE INTO reindex (file)
+SELECT COALESCE(archive, id) FROM file WHERE id IN (SELECT file FROM picture);
+
+PRAGMA user_version = 11;
+`
@@ -0,0 +1,13 @@
+package database
+
+// Ignore patterns are stored per tracked path and deleted along with
+// it.
+const schemaV12 = `
+CREATE TABLE ignore_pattern(
+	tracked_path TEXT NOT NULL REFERENCES tracked_path(path) ON DELETE CASCADE,
+	pattern      TEXT NOT NULL,
+	UNIQUE(tracked_path, pattern)
+);
+
+PRAGMA user_version = 12;
+`
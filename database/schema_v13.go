@@ -0,0 +1,11 @@
+package database
+
+// The options of walking a tracked path. Paths tracked before keep the
+// previous behavior.
+const schemaV13 = `
+ALTER TABLE tracked_path ADD COLUMN hidden INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE tracked_path ADD COLUMN follow_symlinks INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE tracked_path ADD COLUMN xdev INTEGER NOT NULL DEFAULT 0;
+
+PRAGMA user_version = 13;
+`
@@ -0,0 +1,10 @@
+package database
+
+// The name column allows sorting files by their name efficiently.
+const schemaV14 = `
+ALTER TABLE file ADD COLUMN name TEXT;
+UPDATE file SET name = basename(path);
+CREATE INDEX file_name ON file(name);
+
+PRAGMA user_version = 14;
+`
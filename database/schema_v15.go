@@ -0,0 +1,23 @@
+package database
+
+// schemaV15 adds a trigram index for the names of files, which allows
+// searching for parts of names efficiently. The rowid of file_name_text
+// is the ID of the file.
+const schemaV15 = `
+CREATE VIRTUAL TABLE file_name_text USING fts5(
+	name, content = 'file', content_rowid = 'id', tokenize = 'trigram'
+);
+INSERT INTO file_name_text (file_name_text) VALUES ('rebuild');
+CREATE TRIGGER file_name_text_insert AFTER INSERT ON file BEGIN
+	INSERT INTO file_name_text (rowid, name) VALUES (new.id, new.name);
+END;
+CREATE TRIGGER file_name_text_delete AFTER DELETE ON file BEGIN
+	INSERT INTO file_name_text (file_name_text, rowid, name) VALUES ('delete', old.id, old.name);
+END;
+CREATE TRIGGER file_name_text_update AFTER UPDATE OF name ON file BEGIN
+	INSERT INTO file_name_text (file_name_text, rowid, name) VALUES ('delete', old.id, old.name);
+	INSERT INTO file_name_text (rowid, name) VALUES (new.id, new.name);
+END;
+
+PRAGMA user_version = 15;
+`
@@ -0,0 +1,10 @@
+package database
+
+// Earlier versions of den stored the MIME type of OpenDocument
+// presentations with a trailing space.
+const schemaV16 = `
+UPDATE file SET mime = 'application/vnd.oasis.opendocument.presentation'
+WHERE mime = 'application/vnd.oasis.opendocument.presentation ';
+
+PRAGMA user_version = 16;
+`
@@ -0,0 +1,9 @@
+package database
+
+const schemaV2 = `
+ALTER TABLE file ADD COLUMN hash TEXT;
+CREATE INDEX file_hash ON file(hash);
+CREATE INDEX file_size ON file(size);
+
+PRAGMA user_version = 2;
+`
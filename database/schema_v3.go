@@ -0,0 +1,24 @@
+package database
+
+// schemaV3 adds a full-text index for the contents of documents. The
+// rowid of document_text is the ID of the document's file.
+//
+// Documents that were indexed before did not have their text extracted,
+// so the next rescan reindexes them. The reindex table holds the files,
+// that the next rescan indexes anew, because a schema update added
+// information, that was not gathered for them yet.
+const schemaV3 = `
+CREATE TABLE reindex(
+	file INTEGER PRIMARY KEY,
+	FOREIGN KEY(file) REFERENCES file(id) ON DELETE CASCADE
+);
+
+CREATE VIRTUAL TABLE document_text USING fts5(text);
+CREATE TRIGGER document_text_delete AFTER DELETE ON document BEGIN
+	DELETE FROM document_text WHERE rowid = old.file;
+END;
+
+INSERT OR IGNORE INTO reindex (file) SELECT file FROM document;
+
+PRAGMA user_version = 3;
+`
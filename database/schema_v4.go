@@ -0,0 +1,15 @@
+package database
+
+// Tags reference files by path instead of ID, because file entries are
+// deleted and created anew when a file is reindexed. Tags of files that
+// have been removed are deleted with DeleteOrphanedTags.
+const schemaV4 = `
+CREATE TABLE tag(
+	tag  TEXT NOT NULL,
+	path TEXT NOT NULL,
+	UNIQUE(tag, path)
+);
+CREATE INDEX tag_path ON tag(path);
+
+PRAGMA user_version = 4;
+`
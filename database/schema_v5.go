@@ -0,0 +1,23 @@
+package database
+
+// Members of archives are stored as files, which reference the archive
+// they are contained in. Files that fit no category yet are reindexed,
+// because they may be archives.
+const schemaV5 = `
+ALTER TABLE file ADD COLUMN archive INTEGER REFERENCES file(id) ON DELETE CASCADE;
+CREATE INDEX file_archive ON file(archive);
+
+CREATE TABLE archive(
+	file INTEGER PRIMARY KEY,
+	FOREIGN KEY(file) REFERENCES file(id) ON DELETE CASCADE
+);
+
+INSERT OR IGNORE INTO reindex (file)
+SELECT id FROM file
+WHERE NOT EXISTS (SELECT 1 FROM picture p WHERE p.file = file.id)
+AND NOT EXISTS (SELECT 1 FROM video v WHERE v.file = file.id)
+AND NOT EXISTS (SELECT 1 FROM audio a WHERE a.file = file.id)
+AND NOT EXISTS (SELECT 1 FROM document d WHERE d.file = file.id);
+
+PRAGMA user_version = 5;
+`
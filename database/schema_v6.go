@@ -0,0 +1,26 @@
+package database
+
+// Files that fit no category yet and plain text documents are
+// reindexed, because they may be executables.
+const schemaV6 = `
+CREATE TABLE executable(
+	file        INTEGER PRIMARY KEY,
+	arch        TEXT,
+	linking     TEXT,
+	interpreter TEXT,
+	FOREIGN KEY(file) REFERENCES file(id) ON DELETE CASCADE
+);
+CREATE INDEX executable_arch ON executable(arch);
+CREATE INDEX executable_interpreter ON executable(interpreter);
+
+INSERT OR IGNORE INTO reindex (file)
+SELECT id FROM file
+WHERE archive IS NULL
+AND NOT EXISTS (SELECT 1 FROM picture p WHERE p.file = file.id)
+AND NOT EXISTS (SELECT 1 FROM video v WHERE v.file = file.id)
+AND NOT EXISTS (SELECT 1 FROM audio a WHERE a.file = file.id)
+AND NOT EXISTS (SELECT 1 FROM archive r WHERE r.file = file.id)
+AND (mime LIKE 'text/%' OR NOT EXISTS (SELECT 1 FROM document d WHERE d.file = file.id));
+
+PRAGMA user_version = 6;
+`
@@ -0,0 +1,19 @@
+package database
+
+// Audio files are reindexed to gather the new metadata.
+const schemaV7 = `
+ALTER TABLE audio ADD COLUMN album_artist TEXT;
+ALTER TABLE audio ADD COLUMN album TEXT;
+ALTER TABLE audio ADD COLUMN title TEXT;
+ALTER TABLE audio ADD COLUMN genre TEXT;
+ALTER TABLE audio ADD COLUMN track INTEGER;
+ALTER TABLE audio ADD COLUMN disc INTEGER;
+CREATE INDEX audio_album ON audio(album);
+CREATE INDEX audio_title ON audio(title);
+CREATE INDEX audio_genre ON audio(genre);
+
+INSERT OR IGNORE INTO reindex (file)
+SELECT COALESCE(archive, id) FROM file WHERE id IN (SELECT file FROM audio);
+
+PRAGMA user_version = 7;
+`
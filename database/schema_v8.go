@@ -0,0 +1,14 @@
+package database
+
+// Pictures and videos are reindexed to gather the time they were taken.
+const schemaV8 = `
+ALTER TABLE file ADD COLUMN taken INTEGER;
+CREATE INDEX file_taken ON file(taken);
+CREATE INDEX file_created ON file(COALESCE(taken, created_guess));
+
+INSERT OR IGNORE INTO reindex (file)
+SELECT COALESCE(archive, id) FROM file
+WHERE id IN (SELECT file FROM picture UNION SELECT file FROM video);
+
+PRAGMA user_version = 8;
+`
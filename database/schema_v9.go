@@ -0,0 +1,20 @@
+package database
+
+// Pictures and videos are reindexed to gather their location.
+const schemaV9 = `
+ALTER TABLE picture ADD COLUMN latitude REAL;
+ALTER TABLE picture ADD COLUMN longitude REAL;
+ALTER TABLE picture ADD COLUMN altitude REAL;
+CREATE INDEX picture_latitude ON picture(latitude);
+
+ALTER TABLE video ADD COLUMN latitude REAL;
+ALTER TABLE video ADD COLUMN longitude REAL;
+ALTER TABLE video ADD COLUMN altitude REAL;
+CREATE INDEX video_latitude ON video(latitude);
+
+INSERT OR IGNORE INTO reindex (file)
+SELECT COALESCE(archive, id) FROM file
+WHERE id IN (SELECT file FROM picture UNION SELECT file FROM video);
+
+PRAGMA user_version = 9;
+`
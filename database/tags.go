@@ -0,0 +1,84 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TagFiles adds tag to the files with the given paths. All paths must
+// belong to tracked files.
+func (db DB) TagFiles(tag string, paths []string) error {
+	tx, err := db.d.Begin()
+	if err != nil {
+		return fmt.Errorf("could not start transaction: %s", err)
+	}
+	for _, path := range paths {
+		var tracked bool
+		q := `SELECT EXISTS (SELECT 1 FROM file WHERE path = ?)`
+		if err = tx.QueryRow(q, path).Scan(&tracked); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("could not query database: %s", err)
+		} else if !tracked {
+			tx.Rollback()
+			return fmt.Errorf("'%s' is not a tracked file", path)
+		}
+		q = `INSERT INTO tag (tag, path) VALUES (?, ?) ` +
+			`ON CONFLICT (tag, path) DO NOTHING`
+		if _, err = tx.Exec(q, tag, path); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("could not tag '%s': %s", path, err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit transaction: %s", err)
+	}
+	return nil
+}
+
+// UntagFiles removes tag from the files with the given paths.
+func (db DB) UntagFiles(tag string, paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	args := []any{tag}
+	for _, path := range paths {
+		args = append(args, path)
+	}
+	q := `DELETE FROM tag WHERE tag = ? ` +
+		`AND path IN (?` + strings.Repeat(", ?", len(paths)-1) + `)`
+	if _, err := db.d.Exec(q, args...); err != nil {
+		return fmt.Errorf("could not remove tag: %s", err)
+	}
+	return nil
+}
+
+// Tags returns all tags of the files with the given paths, together
+// with the number of these files that carry each tag. If no paths are
+// given, the tags of all files are returned.
+func (db DB) Tags(paths []string) ([]ValueCount, error) {
+	q := `SELECT tag, COUNT(*) FROM tag `
+	var args []any
+	if len(paths) > 0 {
+		q += `WHERE path IN (?` + strings.Repeat(", ?", len(paths)-1) + `) `
+		for _, path := range paths {
+			args = append(args, path)
+		}
+	}
+	q += `GROUP BY tag ORDER BY tag `
+	return db.valueCounts(q, args...)
+}
+
+const deleteOrphanedTags = `DELETE FROM tag WHERE path NOT IN (SELECT path FROM file)`
+
+// DeleteOrphanedTags deletes the tags of files that are not tracked
+// anymore. It must not be called between deleting and adding anew the
+// entry of a reindexed file, because its tags would be lost.
+//
+// db.Begin must have been called to create a transaction before calling
+// DeleteOrphanedTags.
+func (db DB) DeleteOrphanedTags() error {
+	if _, err := db.tx.Exec(deleteOrphanedTags); err != nil {
+		return fmt.Errorf("could not delete tags of removed files: %s", err)
+	}
+	return nil
+}
@@ -2,18 +2,41 @@ package database
 
 import "time"
 
+// Entry is a tracked file of any category. It is implemented by *File,
+// *Picture, *Video, *Audio, *Document, *Archive and *Executable.
+type Entry interface {
+	// Base returns the information common to files of all categories.
+	Base() *File
+}
+
 type File struct {
 	id           int64
+	archive      int64 // The ID of the containing archive, if any.
 	Path         string
 	Size         int64
 	CreatedGuess time.Time
 	Modified     time.Time
 	MIME         string
+
+	// Taken is the time a picture or video was taken, according to its
+	// metadata. It is nil if unknown.
+	Taken *time.Time
 }
 
+func (f *File) Base() *File { return f }
+
 type Picture struct {
 	*File
-	Camera string
+	Camera   string
+	Location *Location
+
+	// Width and Height are given as displayed, i.e. they are swapped
+	// for pictures that are rotated by 90 degrees.
+	Width, Height *int
+
+	// PerceptualHash is the difference hash of the picture. It is nil,
+	// if the picture could not be decoded.
+	PerceptualHash *uint64
 }
 
 type Video struct {
@@ -21,13 +44,73 @@ type Video struct {
 	Duration *time.Duration
 	Camera   string
 	Year     *int
+	Location *Location
+
+	// Width and Height are given as displayed, i.e. they are swapped
+	// for videos that are rotated by 90 degrees.
+	Width, Height *int
+
+	FrameRate              *float64
+	VideoCodec, AudioCodec string
+	Bitrate                *int // In bits per second.
+}
+
+// Location is a position in WGS 84 coordinates.
+type Location struct {
+	Latitude, Longitude float64
+
+	// Altitude is given in meters above sea level. It is nil if
+	// unknown.
+	Altitude *float64
 }
 
 type Audio struct {
 	*File
-	Duration *time.Duration
-	Author   string
-	Year     *int
+	Duration    *time.Duration
+	Author      string
+	Year        *int
+	AlbumArtist string
+	Album       string
+	Title       string
+	Genre       string
+	Track, Disc *int
+	Codec       string
+	Bitrate     *int // In bits per second.
 }
 
-type Document struct{ *File }
+type Document struct {
+	*File
+
+	// Text is the plain text content of the document. It is empty if
+	// the text could not be extracted. Text is not loaded by queries.
+	Text string
+
+	// Snippet is an excerpt of Text that matches the full-text query of
+	// DocumentFilter.Text. The matches are enclosed in brackets.
+	Snippet string
+}
+
+type Executable struct {
+	*File
+
+	// Arch is the architecture a binary was built for, e.g. "amd64".
+	// It is empty for scripts.
+	Arch string
+
+	// Linking is "static" or "dynamic" for binaries and empty for
+	// scripts.
+	Linking string
+
+	// Interpreter is the program that runs a script, e.g. "python3", or
+	// the dynamic loader of a binary.
+	Interpreter string
+}
+
+type Archive struct {
+	*File
+
+	// Members are the files contained in the archive. Their paths
+	// consist of the path of the archive, followed by "!/" and their
+	// path within the archive. Members are not loaded by queries.
+	Members []Entry
+}
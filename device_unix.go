@@ -0,0 +1,18 @@
+//go:build !windows
+
+package den
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// device returns the ID of the device holding the file described by
+// info.
+func device(info fs.FileInfo) (uint64, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(stat.Dev), true
+}
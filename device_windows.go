@@ -0,0 +1,11 @@
+//go:build windows
+
+package den
+
+import "io/fs"
+
+// device is not supported on Windows, so mount points are always
+// crossed.
+func device(info fs.FileInfo) (uint64, bool) {
+	return 0, false
+}
@@ -7,6 +7,7 @@ require (
 	github.com/codesoap/jkls-go-mediainfo v0.0.0-20260106203014-2715d4251ed7
 	github.com/djherbis/times v1.6.0
 	github.com/mattn/go-sqlite3 v1.14.32
+	github.com/ulikunitz/xz v0.5.15
 )
 
 require golang.org/x/sys v0.0.0-20220615213510-4f61da869c0c // indirect
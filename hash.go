@@ -0,0 +1,64 @@
+package den
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/codesoap/den/database"
+)
+
+// hashDuplicateCandidates stores content hashes for all files that
+// share their size with another file, but have not been hashed yet.
+// Files that cannot be read are skipped; they will be tried again on
+// the next run.
+func hashDuplicateCandidates(db database.DB) error {
+	paths, err := db.UnhashedDuplicateCandidates()
+	if err != nil {
+		return fmt.Errorf("could not find files to hash: %s", err)
+	}
+	entriesInTx := 0
+	for _, path := range paths {
+		hash, err := hashFile(path)
+		if err != nil {
+			continue
+		}
+		if entriesInTx == 0 {
+			if err := db.BeginTx(); err != nil {
+				return fmt.Errorf("could not start transaction: %s", err)
+			}
+		}
+		entriesInTx++
+		if err = db.SetHash(path, hash); err != nil {
+			_ = db.Rollback()
+			return fmt.Errorf("could not store hash of '%s': %s", path, err)
+		}
+		if entriesInTx == 1_000 {
+			entriesInTx = 0
+			if err := db.Commit(); err != nil {
+				return fmt.Errorf("could not commit transaction: %s", err)
+			}
+		}
+	}
+	if entriesInTx > 0 {
+		if err := db.Commit(); err != nil {
+			return fmt.Errorf("could not commit transaction: %s", err)
+		}
+	}
+	return nil
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
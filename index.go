@@ -0,0 +1,98 @@
+package den
+
+import (
+	"fmt"
+	"io/fs"
+	"sync"
+	"time"
+
+	"github.com/codesoap/den/database"
+)
+
+type indexJob struct {
+	path string
+	d    fs.DirEntry
+}
+
+type analysis struct {
+	a   addition
+	err error
+}
+
+// indexFiles analyzes the given files with the given number of
+// concurrent workers and adds them to the database. The results are
+// written by a single writer in transactions of up to 1000 entries.
+//
+// prog.Done is incremented for every stored file and progress updates
+// are written roughly once per second.
+func indexFiles(db database.DB, files map[string]fs.DirEntry, jobs int, progress chan Progress, prog *Progress) error {
+	done := make(chan struct{})
+	defer close(done)
+	todo := make(chan indexJob)
+	go func() {
+		defer close(todo)
+		for path, d := range files {
+			select {
+			case todo <- indexJob{path: path, d: d}:
+			case <-done:
+				return
+			}
+		}
+	}()
+	results := make(chan analysis)
+	var wg sync.WaitGroup
+	for range max(1, jobs) {
+		wg.Go(func() {
+			for job := range todo {
+				a, err := analyzeFile(job.path, job.d)
+				select {
+				case results <- analysis{a: a, err: err}:
+				case <-done:
+					return
+				}
+			}
+		})
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	entriesInTx := 0
+	var lastProgressUpdate time.Time
+	for r := range results {
+		if time.Since(lastProgressUpdate) >= time.Second {
+			progress <- *prog
+			lastProgressUpdate = time.Now()
+		}
+		if r.err != nil {
+			if entriesInTx > 0 {
+				_ = db.Rollback()
+			}
+			return r.err
+		}
+		if entriesInTx == 0 {
+			if err := db.BeginTx(); err != nil {
+				return fmt.Errorf("could not start transaction: %s", err)
+			}
+		}
+		entriesInTx++
+		if err := storeFile(db, r.a); err != nil {
+			_ = db.Rollback()
+			return err
+		}
+		if entriesInTx == 1_000 {
+			entriesInTx = 0
+			if err := db.Commit(); err != nil {
+				return fmt.Errorf("could not commit transaction: %s", err)
+			}
+		}
+		prog.Done++
+	}
+	if entriesInTx > 0 {
+		if err := db.Commit(); err != nil {
+			return fmt.Errorf("could not commit transaction: %s", err)
+		}
+	}
+	return nil
+}
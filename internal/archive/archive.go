@@ -0,0 +1,157 @@
+// Package archive lists the members of archives without extracting
+// them. Supported are zip and tar archives, where tar archives may be
+// compressed with gzip, bzip2 or xz.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ulikunitz/xz"
+)
+
+// MaxMembers is the maximum number of members that is listed for a
+// single archive. Everything beyond is ignored.
+const MaxMembers = 100_000
+
+// HeadLength is the number of bytes of each member that is made
+// available in Member.Head.
+const HeadLength = 512
+
+// Member is a regular file within an archive.
+type Member struct {
+	// Name is the slash separated path of the member within the
+	// archive, without a leading slash.
+	Name     string
+	Size     int64
+	Modified time.Time
+
+	// Head holds the first bytes of the member's content, so that its
+	// type can be determined.
+	Head []byte
+}
+
+// List returns the members of the archive at path. If the MIME type is
+// not supported, nil and no error is returned.
+func List(path, mime string) ([]Member, error) {
+	switch mime {
+	case "application/zip":
+		return listZip(path)
+	case "application/x-tar":
+		return listTar(path, nil)
+	case "application/gzip", "application/x-gzip":
+		return listTar(path, func(r io.Reader) (io.Reader, error) {
+			return gzip.NewReader(r)
+		})
+	case "application/x-bzip2":
+		return listTar(path, func(r io.Reader) (io.Reader, error) {
+			return bzip2.NewReader(r), nil
+		})
+	case "application/x-xz":
+		return listTar(path, func(r io.Reader) (io.Reader, error) {
+			return xz.NewReader(r)
+		})
+	}
+	return nil, nil
+}
+
+func listZip(path string) ([]Member, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var members []Member
+	for _, f := range r.File {
+		if len(members) == MaxMembers {
+			break
+		} else if !f.Mode().IsRegular() {
+			continue
+		}
+		m := Member{
+			Name:     cleanName(f.Name),
+			Size:     int64(f.UncompressedSize64),
+			Modified: f.Modified,
+		}
+		if m.Head, err = readHead(f); err != nil {
+			return nil, fmt.Errorf("could not read '%s': %s", f.Name, err)
+		}
+		members = append(members, m)
+	}
+	return members, nil
+}
+
+// readHead reads the first HeadLength bytes of a zipped file.
+func readHead(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return readAtMost(rc, HeadLength)
+}
+
+// listTar lists the members of a tar archive, which is decompressed
+// with decompress, if it is not nil. If the decompressed file is not a
+// tar archive, nil and no error is returned.
+func listTar(path string, decompress func(io.Reader) (io.Reader, error)) ([]Member, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var r io.Reader = bufio.NewReader(f)
+	if decompress != nil {
+		if r, err = decompress(r); err != nil {
+			return nil, err
+		}
+	}
+	tr := tar.NewReader(r)
+	var members []Member
+	for len(members) < MaxMembers {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err == tar.ErrHeader && len(members) == 0 {
+			// Just a compressed file, not a tar archive.
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		m := Member{
+			Name:     cleanName(header.Name),
+			Size:     header.Size,
+			Modified: header.ModTime,
+		}
+		if m.Head, err = readAtMost(tr, HeadLength); err != nil {
+			return nil, fmt.Errorf("could not read '%s': %s", header.Name, err)
+		}
+		members = append(members, m)
+	}
+	return members, nil
+}
+
+func readAtMost(r io.Reader, n int) ([]byte, error) {
+	buf := make([]byte, n)
+	n, err := io.ReadFull(r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	return buf[:n], err
+}
+
+func cleanName(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
@@ -0,0 +1,174 @@
+// Package doctext extracts the plain text of documents, so that it can
+// be searched. Supported are plain text files, Office Open XML
+// documents (docx, xlsx, pptx) and OpenDocument files (odt, ods, odp).
+package doctext
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxLength is the maximum number of bytes that is extracted from a
+// single document. Everything beyond is ignored.
+const MaxLength = 4 << 20
+
+// textualApplicationTypes are MIME types that do not start with "text/",
+// but still contain plain text.
+var textualApplicationTypes = []string{
+	"application/json",
+	"application/x-openscad",
+	"application/x-perl",
+	"application/x-php",
+	"application/x-powershell",
+	"application/x-ruby",
+	"application/x-troff-man",
+	"application/xml",
+	"application/yaml",
+}
+
+// Extract returns the text of the document at path. If the MIME type is
+// not supported, an empty string and no error is returned.
+func Extract(path, mime string) (string, error) {
+	switch {
+	case strings.HasPrefix(mime, "text/"),
+		slices.Contains(textualApplicationTypes, mime):
+		return extractPlain(path)
+	case mime == "application/vnd.openxmlformats-officedocument.wordprocessingml.document":
+		return extractZipped(path, func(name string) bool {
+			return name == "word/document.xml"
+		})
+	case mime == "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":
+		return extractZipped(path, func(name string) bool {
+			return name == "xl/sharedStrings.xml"
+		})
+	case mime == "application/vnd.openxmlformats-officedocument.presentationml.presentation":
+		return extractZipped(path, func(name string) bool {
+			file, ok := strings.CutPrefix(name, "ppt/slides/")
+			return ok && !strings.Contains(file, "/") && strings.HasSuffix(file, ".xml")
+		})
+	case mime == "application/vnd.oasis.opendocument.text",
+		mime == "application/vnd.oasis.opendocument.spreadsheet",
+		mime == "application/vnd.oasis.opendocument.presentation":
+		return extractZipped(path, func(name string) bool {
+			return name == "content.xml"
+		})
+	}
+	return "", nil
+}
+
+func extractPlain(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	buf, err := io.ReadAll(io.LimitReader(f, MaxLength))
+	if err != nil {
+		return "", err
+	}
+	if slices.Contains(buf, 0) {
+		// Not actually plain text.
+		return "", nil
+	}
+	if !utf8.Valid(buf) {
+		return strings.ToValidUTF8(string(buf), ""), nil
+	}
+	return string(buf), nil
+}
+
+// extractZipped extracts the text of all XML files inside the zip file
+// at path, for which include returns true. The files are processed in
+// natural order, so that e.g. slide2.xml comes before slide10.xml.
+func extractZipped(path string, include func(name string) bool) (string, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	files := make([]*zip.File, 0)
+	for _, f := range r.File {
+		if include(f.Name) {
+			files = append(files, f)
+		}
+	}
+	slices.SortFunc(files, func(a, b *zip.File) int {
+		return compareNatural(a.Name, b.Name)
+	})
+	var sb strings.Builder
+	for _, f := range files {
+		if err := extractXML(f, &sb); err != nil {
+			return "", fmt.Errorf("could not read '%s': %s", f.Name, err)
+		}
+		if sb.Len() >= MaxLength {
+			break
+		}
+	}
+	return sb.String(), nil
+}
+
+// extractXML writes the character data of f to sb. Paragraphs, table
+// rows and similar elements are terminated with a newline.
+func extractXML(f *zip.File, sb *strings.Builder) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	d := xml.NewDecoder(rc)
+	for sb.Len() < MaxLength {
+		t, err := d.Token()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		switch t := t.(type) {
+		case xml.CharData:
+			sb.Write(t)
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "p", "h", "si", "tr", "table-row":
+				sb.WriteByte('\n')
+			case "tab", "tc", "table-cell":
+				sb.WriteByte('\t')
+			}
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "br", "line-break":
+				sb.WriteByte('\n')
+			case "s":
+				sb.WriteByte(' ')
+			}
+		}
+	}
+	return nil
+}
+
+// compareNatural compares a and b like strings.Compare, but treats
+// trailing numbers before the file extension numerically.
+func compareNatural(a, b string) int {
+	aPrefix, aNum := splitNumber(a)
+	bPrefix, bNum := splitNumber(b)
+	if aPrefix != bPrefix {
+		return strings.Compare(aPrefix, bPrefix)
+	}
+	return aNum - bNum
+}
+
+func splitNumber(name string) (string, int) {
+	name = strings.TrimSuffix(name, path.Ext(name))
+	i := len(name)
+	for i > 0 && name[i-1] >= '0' && name[i-1] <= '9' {
+		i--
+	}
+	num, _ := strconv.Atoi(name[i:])
+	return name[:i], num
+}
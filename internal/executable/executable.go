@@ -0,0 +1,120 @@
+// Package executable recognizes executables and gathers information
+// about them. Supported are ELF binaries and scripts starting with a
+// shebang line.
+package executable
+
+import (
+	"bytes"
+	"debug/elf"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+type Info struct {
+	// Arch is the architecture a binary was built for, using the names
+	// of GOARCH where possible, e.g. "amd64". It is empty for scripts.
+	Arch string
+
+	// Linking is "static" or "dynamic" for binaries and empty for
+	// scripts.
+	Linking string
+
+	// Interpreter is the name of the program that runs a script, e.g.
+	// "python3", or of the dynamic loader of a binary.
+	Interpreter string
+}
+
+var archNames = map[elf.Machine]string{
+	elf.EM_386:     "386",
+	elf.EM_X86_64:  "amd64",
+	elf.EM_ARM:     "arm",
+	elf.EM_AARCH64: "arm64",
+	elf.EM_RISCV:   "riscv64",
+	elf.EM_PPC64:   "ppc64",
+	elf.EM_S390:    "s390x",
+	elf.EM_MIPS:    "mips",
+}
+
+// Inspect returns information about the file at path. If the file is
+// neither an ELF binary nor a script, false is returned.
+func Inspect(path string) (Info, bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Info{}, false, err
+	}
+	defer f.Close()
+	head := make([]byte, 256)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return Info{}, false, err
+	}
+	head = head[:n]
+	if bytes.HasPrefix(head, []byte(elf.ELFMAG)) {
+		info, err := inspectELF(f)
+		return info, err == nil, err
+	} else if interpreter, ok := Interpreter(head); ok {
+		return Info{Interpreter: interpreter}, true, nil
+	}
+	return Info{}, false, nil
+}
+
+// maxInterpreterLength is the longest path of a dynamic loader, that is
+// read. Longer ones are rejected, because their length is taken from
+// the file.
+const maxInterpreterLength = 4096
+
+func inspectELF(r io.ReaderAt) (Info, error) {
+	f, err := elf.NewFile(r)
+	if err != nil {
+		return Info{}, err
+	}
+	defer f.Close()
+	info := Info{Arch: archNames[f.Machine], Linking: "static"}
+	if info.Arch == "" {
+		info.Arch = strings.ToLower(strings.TrimPrefix(f.Machine.String(), "EM_"))
+	}
+	for _, prog := range f.Progs {
+		switch prog.Type {
+		case elf.PT_INTERP:
+			if prog.Filesz > maxInterpreterLength {
+				return info, fmt.Errorf("interpreter path is %d bytes long", prog.Filesz)
+			}
+			buf := make([]byte, prog.Filesz)
+			if _, err := prog.ReadAt(buf, 0); err != nil {
+				return info, err
+			}
+			info.Interpreter = path.Base(string(bytes.TrimRight(buf, "\x00")))
+			info.Linking = "dynamic"
+		case elf.PT_DYNAMIC:
+			info.Linking = "dynamic"
+		}
+	}
+	return info, nil
+}
+
+// Interpreter returns the name of the interpreter given in the shebang
+// line at the start of head. "/usr/bin/env" is skipped, so that e.g.
+// "#!/usr/bin/env python3" yields "python3".
+func Interpreter(head []byte) (string, bool) {
+	line, ok := bytes.CutPrefix(head, []byte("#!"))
+	if !ok {
+		return "", false
+	}
+	line, _, _ = bytes.Cut(line, []byte("\n"))
+	fields := strings.Fields(string(line))
+	if len(fields) > 0 && path.Base(fields[0]) == "env" {
+		fields = fields[1:]
+		for len(fields) > 0 &&
+			(strings.HasPrefix(fields[0], "-") || strings.Contains(fields[0], "=")) {
+			// Skip options and variable assignments.
+			fields = fields[1:]
+		}
+	}
+	if len(fields) == 0 {
+		return "", false
+	}
+	return path.Base(fields[0]), true
+}
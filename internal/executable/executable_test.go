@@ -0,0 +1,103 @@
+package executable
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// buildELF returns a 64-bit ELF binary for amd64, whose only program
+// header is a PT_INTERP segment of the given size, that holds interp.
+func buildELF(t *testing.T, interp string, size uint64) []byte {
+	t.Helper()
+	const headerSize, progSize = 64, 56
+	header := elf.Header64{
+		Type:      uint16(elf.ET_EXEC),
+		Machine:   uint16(elf.EM_X86_64),
+		Version:   uint32(elf.EV_CURRENT),
+		Phoff:     headerSize,
+		Ehsize:    headerSize,
+		Phentsize: progSize,
+		Phnum:     1,
+	}
+	copy(header.Ident[:], elf.ELFMAG)
+	header.Ident[elf.EI_CLASS] = byte(elf.ELFCLASS64)
+	header.Ident[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+	header.Ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+	prog := elf.Prog64{
+		Type:   uint32(elf.PT_INTERP),
+		Off:    headerSize + progSize,
+		Filesz: size,
+		Memsz:  size,
+	}
+	var b bytes.Buffer
+	for _, v := range []any{header, prog} {
+		if err := binary.Write(&b, binary.LittleEndian, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b.WriteString(interp)
+	return b.Bytes()
+}
+
+func TestInspect(t *testing.T) {
+	loader := "/lib64/ld-linux-x86-64.so.2\x00"
+	tests := []struct {
+		name    string
+		content []byte
+		want    Info
+		ok      bool
+		wantErr bool
+	}{
+		{"dynamic", buildELF(t, loader, uint64(len(loader))),
+			Info{Arch: "amd64", Linking: "dynamic", Interpreter: "ld-linux-x86-64.so.2"}, true, false},
+		{"truncated interpreter", buildELF(t, loader[:10], uint64(len(loader))), Info{}, false, true},
+		{"huge interpreter", buildELF(t, loader, 1<<40), Info{}, false, true},
+		{"script", []byte("#!/usr/bin/env python3\nprint()\n"), Info{Interpreter: "python3"}, true, false},
+		{"text", []byte("hello\n"), Info{}, false, false},
+		{"empty", nil, Info{}, false, false},
+	}
+	dir := t.TempDir()
+	for _, test := range tests {
+		path := filepath.Join(dir, test.name)
+		if err := os.WriteFile(path, test.content, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		info, ok, err := Inspect(path)
+		if (err != nil) != test.wantErr {
+			t.Errorf("Inspect(%s) returned error %v, want error: %t", test.name, err, test.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if info != test.want || ok != test.ok {
+			t.Errorf("Inspect(%s) = %+v, %t, want %+v, %t", test.name, info, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestInterpreter(t *testing.T) {
+	tests := []struct {
+		head string
+		want string
+		ok   bool
+	}{
+		{"#!/bin/sh\necho", "sh", true},
+		{"#! /usr/bin/python3 -u\n", "python3", true},
+		{"#!/usr/bin/env python3\n", "python3", true},
+		{"#!/usr/bin/env -S LC_ALL=C perl -w\n", "perl", true},
+		{"#!/usr/bin/env\n", "", false},
+		{"#!\n", "", false},
+		{"echo\n", "", false},
+	}
+	for _, test := range tests {
+		got, ok := Interpreter([]byte(test.head))
+		if got != test.want || ok != test.ok {
+			t.Errorf("Interpreter(%q) = %q, %t, want %q, %t", test.head, got, ok, test.want, test.ok)
+		}
+	}
+}
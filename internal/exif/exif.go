@@ -0,0 +1,341 @@
+// Package exif reads the time and location a picture was taken from its
+// Exif data. Supported are JPEG files and TIFF based files, which
+// includes most raw formats of cameras. The XMP data of JPEG files is
+// also considered for the location.
+package exif
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errNoExif is returned while parsing, if a file contains no Exif data.
+var errNoExif = errors.New("no Exif data found")
+
+const (
+	tagOrientation        = 0x0112
+	tagExifIFD            = 0x8769
+	tagGPSIFD             = 0x8825
+	tagDateTimeOriginal   = 0x9003
+	tagOffsetTimeOriginal = 0x9011
+
+	tagGPSLatitudeRef  = 1
+	tagGPSLatitude     = 2
+	tagGPSLongitudeRef = 3
+	tagGPSLongitude    = 4
+	tagGPSAltitudeRef  = 5
+	tagGPSAltitude     = 6
+
+	typeByte     = 1
+	typeASCII    = 2
+	typeShort    = 3
+	typeLong     = 4
+	typeRational = 5
+)
+
+// typeSizes holds the size in bytes of a single value of each type.
+var typeSizes = map[uint16]uint64{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8}
+
+// Metadata holds the information found in a picture. Unknown values are
+// nil.
+type Metadata struct {
+	Taken    *time.Time
+	Location *Location
+
+	// Rotated is true, if the picture is displayed rotated by 90
+	// degrees, so that its width and height are swapped.
+	Rotated bool
+}
+
+// Location is a position in WGS 84 coordinates.
+type Location struct {
+	Latitude, Longitude float64
+
+	// Altitude is given in meters above sea level. It is nil if
+	// unknown.
+	Altitude *float64
+}
+
+// maxExifLength limits the amount of data read from TIFF based files.
+const maxExifLength = 1 << 20
+
+// Read returns the metadata of the picture at path. If the time zone
+// offset of the capture date is not recorded, the local time zone is
+// assumed. Files without metadata yield an empty Metadata.
+func Read(path string) (Metadata, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	magic, err := r.Peek(4)
+	if err != nil {
+		return Metadata{}, nil
+	}
+	var tiff, xmp []byte
+	switch {
+	case magic[0] == 0xFF && magic[1] == 0xD8:
+		tiff, xmp = jpegSegments(r)
+	case string(magic) == "II*\x00" || string(magic) == "MM\x00*":
+		if tiff, err = io.ReadAll(io.LimitReader(r, maxExifLength)); err != nil {
+			return Metadata{}, err
+		}
+	}
+	var m Metadata
+	if tiff != nil {
+		if m, err = parseTIFF(tiff); err != nil && err != errNoExif {
+			return m, err
+		}
+	}
+	if m.Location == nil && xmp != nil {
+		m.Location = parseXMPLocation(xmp)
+	}
+	return m, nil
+}
+
+// jpegSegments returns the TIFF structure of the Exif segment and the
+// XMP packet of a JPEG file. Missing ones are nil.
+func jpegSegments(r *bufio.Reader) (tiff, xmp []byte) {
+	if _, err := r.Discard(2); err != nil {
+		return nil, nil
+	}
+	for tiff == nil || xmp == nil {
+		var header [4]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			break
+		}
+		marker := header[1]
+		if header[0] != 0xFF || marker == 0xDA || marker == 0xD9 {
+			// Image data starts; there is no more metadata.
+			break
+		}
+		length := int(binary.BigEndian.Uint16(header[2:])) - 2
+		if length < 0 {
+			break
+		}
+		if marker != 0xE1 {
+			if _, err := r.Discard(length); err != nil {
+				break
+			}
+			continue
+		}
+		segment := make([]byte, length)
+		if _, err := io.ReadFull(r, segment); err != nil {
+			break
+		}
+		if t, ok := bytes.CutPrefix(segment, []byte("Exif\x00\x00")); ok && tiff == nil {
+			tiff = t
+		} else if x, ok := bytes.CutPrefix(segment, []byte("http://ns.adobe.com/xap/1.0/\x00")); ok && xmp == nil {
+			xmp = x
+		}
+	}
+	return tiff, xmp
+}
+
+func parseTIFF(tiff []byte) (Metadata, error) {
+	var m Metadata
+	if len(tiff) < 8 {
+		return m, errNoExif
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return m, errNoExif
+	}
+	ifd0 := readIFD(tiff, order, order.Uint32(tiff[4:]))
+	if o, ok := ifd0[tagOrientation]; ok && o.typ == typeShort {
+		// Orientations 5 to 8 are transposed or rotated by 90 degrees.
+		m.Rotated = order.Uint16(o.value) >= 5 && order.Uint16(o.value) <= 8
+	}
+	if gpsIFD, ok := ifd0[tagGPSIFD]; ok && gpsIFD.typ == typeLong {
+		m.Location = parseGPS(readIFD(tiff, order, order.Uint32(gpsIFD.value)), order)
+	}
+	exifIFD, ok := ifd0[tagExifIFD]
+	if !ok || exifIFD.typ != typeLong {
+		return m, nil
+	}
+	exif := readIFD(tiff, order, order.Uint32(exifIFD.value))
+	date, ok := exif[tagDateTimeOriginal]
+	if !ok || date.typ != typeASCII {
+		return m, nil
+	}
+	loc := time.Local
+	if offset, ok := exif[tagOffsetTimeOriginal]; ok && offset.typ == typeASCII {
+		if t, err := time.Parse("-07:00", asciiValue(offset.value)); err == nil {
+			loc = t.Location()
+		}
+	}
+	t, err := time.ParseInLocation("2006:01:02 15:04:05", asciiValue(date.value), loc)
+	if err != nil {
+		return m, fmt.Errorf("invalid capture date: %s", err)
+	}
+	m.Taken = &t
+	return m, nil
+}
+
+// parseGPS returns the location stored in the entries of a GPS IFD. It
+// returns nil, if the entries contain no valid location.
+func parseGPS(gps map[uint16]entry, order binary.ByteOrder) *Location {
+	lat, latOK := degrees(gps[tagGPSLatitude], order)
+	lon, lonOK := degrees(gps[tagGPSLongitude], order)
+	if !latOK || !lonOK || math.Abs(lat) > 90 || math.Abs(lon) > 180 {
+		return nil
+	}
+	if asciiValue(gps[tagGPSLatitudeRef].value) == "S" {
+		lat = -lat
+	}
+	if asciiValue(gps[tagGPSLongitudeRef].value) == "W" {
+		lon = -lon
+	}
+	l := &Location{Latitude: lat, Longitude: lon}
+	if alt, ok := gps[tagGPSAltitude]; ok && alt.typ == typeRational && len(alt.value) >= 8 {
+		if a, ok := rational(alt.value, order); ok {
+			if ref := gps[tagGPSAltitudeRef]; ref.typ == typeByte && len(ref.value) > 0 && ref.value[0] == 1 {
+				a = -a // Below sea level.
+			}
+			l.Altitude = &a
+		}
+	}
+	return l
+}
+
+// degrees converts a GPS coordinate, given as degrees, minutes and
+// seconds, to decimal degrees.
+func degrees(e entry, order binary.ByteOrder) (float64, bool) {
+	if e.typ != typeRational || len(e.value) < 24 {
+		return 0, false
+	}
+	var d float64
+	for i, unit := range []float64{1, 60, 3600} {
+		v, ok := rational(e.value[i*8:], order)
+		if !ok {
+			return 0, false
+		}
+		d += v / unit
+	}
+	return d, true
+}
+
+func rational(b []byte, order binary.ByteOrder) (float64, bool) {
+	numerator, denominator := order.Uint32(b), order.Uint32(b[4:])
+	if denominator == 0 {
+		return 0, false
+	}
+	return float64(numerator) / float64(denominator), true
+}
+
+var (
+	xmpLatitude    = regexp.MustCompile(`exif:GPSLatitude(?:="|>)([^"<]*)`)
+	xmpLongitude   = regexp.MustCompile(`exif:GPSLongitude(?:="|>)([^"<]*)`)
+	xmpAltitude    = regexp.MustCompile(`exif:GPSAltitude(?:="|>)([^"<]*)`)
+	xmpAltitudeRef = regexp.MustCompile(`exif:GPSAltitudeRef(?:="|>)([^"<]*)`)
+)
+
+// parseXMPLocation returns the location stored in an XMP packet, where
+// coordinates look like "51,30.5N" or "51,30,30N". It returns nil, if
+// the packet contains no valid location.
+func parseXMPLocation(xmp []byte) *Location {
+	lat, latOK := xmpCoordinate(xmpLatitude.FindSubmatch(xmp), "S")
+	lon, lonOK := xmpCoordinate(xmpLongitude.FindSubmatch(xmp), "W")
+	if !latOK || !lonOK || math.Abs(lat) > 90 || math.Abs(lon) > 180 {
+		return nil
+	}
+	l := &Location{Latitude: lat, Longitude: lon}
+	if match := xmpAltitude.FindSubmatch(xmp); match != nil {
+		numerator, denominator, _ := strings.Cut(string(match[1]), "/")
+		a, err := strconv.ParseFloat(numerator, 64)
+		d, derr := strconv.ParseFloat(denominator, 64)
+		if err == nil && (denominator == "" || derr == nil && d != 0) {
+			if denominator != "" {
+				a /= d
+			}
+			if ref := xmpAltitudeRef.FindSubmatch(xmp); ref != nil && string(ref[1]) == "1" {
+				a = -a // Below sea level.
+			}
+			l.Altitude = &a
+		}
+	}
+	return l
+}
+
+// xmpCoordinate parses the coordinate in the first submatch of match.
+// The coordinate is negated if its direction is negative.
+func xmpCoordinate(match [][]byte, negative string) (float64, bool) {
+	if match == nil || len(match[1]) < 2 {
+		return 0, false
+	}
+	s := string(match[1])
+	value, direction := s[:len(s)-1], s[len(s)-1:]
+	if !strings.Contains("NSEW", direction) {
+		return 0, false
+	}
+	var d float64
+	for i, part := range strings.Split(value, ",") {
+		if i > 2 {
+			return 0, false
+		}
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0, false
+		}
+		d += v / math.Pow(60, float64(i))
+	}
+	if direction == negative {
+		d = -d
+	}
+	return d, true
+}
+
+type entry struct {
+	typ   uint16
+	value []byte
+}
+
+// readIFD reads the entries of the image file directory at offset. The
+// value of each entry is resolved, if it does not fit into the entry
+// itself. Invalid entries are skipped.
+func readIFD(tiff []byte, order binary.ByteOrder, offset uint32) map[uint16]entry {
+	entries := make(map[uint16]entry)
+	if uint64(offset)+2 > uint64(len(tiff)) {
+		return entries
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	for i := range count {
+		start := uint64(offset) + 2 + uint64(i)*12
+		if start+12 > uint64(len(tiff)) {
+			break
+		}
+		raw := tiff[start : start+12]
+		e := entry{typ: order.Uint16(raw[2:]), value: raw[8:12]}
+		n := uint64(order.Uint32(raw[4:])) * typeSizes[e.typ]
+		if n > 4 {
+			valueOffset := uint64(order.Uint32(raw[8:]))
+			if valueOffset+n > uint64(len(tiff)) {
+				continue
+			}
+			e.value = tiff[valueOffset : valueOffset+n]
+		}
+		entries[order.Uint16(raw)] = e
+	}
+	return entries
+}
+
+func asciiValue(b []byte) string {
+	s, _, _ := strings.Cut(string(b), "\x00")
+	return strings.TrimSpace(s)
+}
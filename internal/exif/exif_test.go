@@ -0,0 +1,346 @@
+package exif
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type byteOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+// field is an entry of an IFD built by buildTIFF. If value is nil, the
+// field points to the IFD with the index ifd.
+type field struct {
+	tag, typ uint16
+	count    uint32
+	value    []byte
+	ifd      int
+}
+
+// buildTIFF returns a TIFF structure with the given IFDs, the first of
+// which is IFD0. Values longer than four bytes are stored after the
+// IFDs.
+func buildTIFF(order byteOrder, ifds ...[]field) []byte {
+	offsets := make([]uint32, len(ifds))
+	pos := uint32(8)
+	for i, fields := range ifds {
+		offsets[i] = pos
+		pos += 2 + 12*uint32(len(fields)) + 4
+	}
+	tiff := []byte("II*\x00")
+	if order == binary.BigEndian {
+		tiff = []byte("MM\x00*")
+	}
+	tiff = order.AppendUint32(tiff, offsets[0])
+	var data []byte
+	for _, fields := range ifds {
+		tiff = order.AppendUint16(tiff, uint16(len(fields)))
+		for _, f := range fields {
+			tiff = order.AppendUint16(tiff, f.tag)
+			tiff = order.AppendUint16(tiff, f.typ)
+			switch {
+			case f.value == nil:
+				tiff = order.AppendUint32(tiff, 1)
+				tiff = order.AppendUint32(tiff, offsets[f.ifd])
+			case len(f.value) <= 4:
+				tiff = order.AppendUint32(tiff, f.count)
+				tiff = append(tiff, f.value...)
+				tiff = append(tiff, make([]byte, 4-len(f.value))...)
+			default:
+				tiff = order.AppendUint32(tiff, f.count)
+				tiff = order.AppendUint32(tiff, pos+uint32(len(data)))
+				data = append(data, f.value...)
+			}
+		}
+		tiff = order.AppendUint32(tiff, 0) // No next IFD.
+	}
+	return append(tiff, data...)
+}
+
+func ascii(tag uint16, s string) field {
+	return field{tag: tag, typ: typeASCII, count: uint32(len(s) + 1), value: []byte(s + "\x00")}
+}
+
+func short(order byteOrder, tag, v uint16) field {
+	return field{tag: tag, typ: typeShort, count: 1, value: order.AppendUint16(nil, v)}
+}
+
+// rationals returns a field holding the rationals given as pairs of
+// numerator and denominator.
+func rationals(order byteOrder, tag uint16, pairs ...uint32) field {
+	var b []byte
+	for _, v := range pairs {
+		b = order.AppendUint32(b, v)
+	}
+	return field{tag: tag, typ: typeRational, count: uint32(len(pairs) / 2), value: b}
+}
+
+func pointer(tag uint16, ifd int) field {
+	return field{tag: tag, typ: typeLong, ifd: ifd}
+}
+
+// london returns GPS fields for 51°30'30"N 0°7'30"W at 35.5 m.
+func london(order byteOrder) []field {
+	return []field{
+		ascii(tagGPSLatitudeRef, "N"),
+		rationals(order, tagGPSLatitude, 51, 1, 30, 1, 30, 1),
+		ascii(tagGPSLongitudeRef, "W"),
+		rationals(order, tagGPSLongitude, 0, 1, 75, 10, 0, 1),
+		{tag: tagGPSAltitudeRef, typ: typeByte, count: 1, value: []byte{0}},
+		rationals(order, tagGPSAltitude, 355, 10),
+	}
+}
+
+func TestParseTIFF(t *testing.T) {
+	le, be := binary.LittleEndian, binary.BigEndian
+	plus2 := time.FixedZone("", 2*60*60)
+	tests := []struct {
+		name     string
+		tiff     []byte
+		taken    *time.Time
+		location *Location
+		rotated  bool
+	}{
+		{
+			name: "little endian",
+			tiff: buildTIFF(le,
+				[]field{short(le, tagOrientation, 6), pointer(tagExifIFD, 1), pointer(tagGPSIFD, 2)},
+				[]field{ascii(tagDateTimeOriginal, "2019:05:17 13:14:15"), ascii(tagOffsetTimeOriginal, "+02:00")},
+				london(le),
+			),
+			taken:    ptr(time.Date(2019, 5, 17, 13, 14, 15, 0, plus2)),
+			location: &Location{Latitude: 51 + 30.0/60 + 30.0/3600, Longitude: -0.125, Altitude: ptr(35.5)},
+			rotated:  true,
+		},
+		{
+			name: "big endian",
+			tiff: buildTIFF(be,
+				[]field{short(be, tagOrientation, 3), pointer(tagGPSIFD, 2), pointer(tagExifIFD, 1)},
+				[]field{ascii(tagDateTimeOriginal, "2001:02:03 04:05:06")},
+				[]field{
+					ascii(tagGPSLatitudeRef, "S"),
+					rationals(be, tagGPSLatitude, 33, 1, 51, 1, 36, 1),
+					ascii(tagGPSLongitudeRef, "E"),
+					rationals(be, tagGPSLongitude, 151, 1, 12, 1, 36, 1),
+					{tag: tagGPSAltitudeRef, typ: typeByte, count: 1, value: []byte{1}},
+					rationals(be, tagGPSAltitude, 10, 1),
+				},
+			),
+			taken:    ptr(time.Date(2001, 2, 3, 4, 5, 6, 0, time.Local)),
+			location: &Location{Latitude: -33.86, Longitude: 151.21, Altitude: ptr(-10.0)},
+		},
+		{
+			name: "without altitude",
+			tiff: buildTIFF(le, []field{pointer(tagGPSIFD, 1)}, london(le)[:4]),
+			location: &Location{
+				Latitude:  51 + 30.0/60 + 30.0/3600,
+				Longitude: -0.125,
+			},
+		},
+		{
+			name: "invalid offset",
+			tiff: buildTIFF(le, []field{short(le, tagOrientation, 8), pointer(tagExifIFD, 1)},
+				[]field{ascii(tagOffsetTimeOriginal, "+02:00")}),
+			rotated: true,
+		},
+		{
+			name: "zero denominator",
+			tiff: buildTIFF(le, []field{pointer(tagGPSIFD, 1)}, []field{
+				ascii(tagGPSLatitudeRef, "N"),
+				rationals(le, tagGPSLatitude, 51, 0, 30, 1, 30, 1),
+				ascii(tagGPSLongitudeRef, "W"),
+				rationals(le, tagGPSLongitude, 0, 1, 7, 1, 30, 1),
+			}),
+		},
+		{
+			name: "latitude out of range",
+			tiff: buildTIFF(le, []field{pointer(tagGPSIFD, 1)}, []field{
+				rationals(le, tagGPSLatitude, 91, 1, 0, 1, 0, 1),
+				rationals(le, tagGPSLongitude, 0, 1, 0, 1, 0, 1),
+			}),
+		},
+		{
+			name: "wrong types",
+			tiff: buildTIFF(le,
+				[]field{{tag: tagOrientation, typ: typeLong, count: 1, value: le.AppendUint32(nil, 6)},
+					pointer(tagExifIFD, 1)},
+				[]field{{tag: tagDateTimeOriginal, typ: typeByte, count: 2, value: []byte{1, 2}}},
+			),
+		},
+	}
+	for _, test := range tests {
+		m, err := parseTIFF(test.tiff)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		if !equalTimes(m.Taken, test.taken) {
+			t.Errorf("%s: taken %v, want %v", test.name, m.Taken, test.taken)
+		}
+		if !equalLocations(m.Location, test.location) {
+			t.Errorf("%s: location %s, want %s", test.name, formatLocation(m.Location), formatLocation(test.location))
+		}
+		if m.Rotated != test.rotated {
+			t.Errorf("%s: rotated %t, want %t", test.name, m.Rotated, test.rotated)
+		}
+	}
+}
+
+func TestParseTIFFErrors(t *testing.T) {
+	le := binary.LittleEndian
+	valid := buildTIFF(le, []field{pointer(tagGPSIFD, 1)}, london(le))
+	for _, tiff := range [][]byte{nil, []byte("II*\x00"), []byte("XX*\x00\x08\x00\x00\x00")} {
+		if _, err := parseTIFF(tiff); err != errNoExif {
+			t.Errorf("parseTIFF(%q) returned %v, want %v", tiff, err, errNoExif)
+		}
+	}
+	// Truncated data must not cause a panic:
+	for i := range valid {
+		_, _ = parseTIFF(valid[:i])
+	}
+	invalidDate := buildTIFF(le, []field{pointer(tagExifIFD, 1)},
+		[]field{ascii(tagDateTimeOriginal, "0000:00:00 00:00:00")})
+	if m, err := parseTIFF(invalidDate); err == nil {
+		t.Errorf("parseTIFF with invalid date returned %v", m.Taken)
+	}
+}
+
+func TestParseXMPLocation(t *testing.T) {
+	tests := []struct {
+		xmp  string
+		want *Location
+	}{
+		{`<rdf:Description exif:GPSLatitude="51,30.5N" exif:GPSLongitude="0,7.5W"/>`,
+			&Location{Latitude: 51 + 30.5/60, Longitude: -0.125}},
+		{`<exif:GPSLatitude>33,51,36S</exif:GPSLatitude><exif:GPSLongitude>151,12,36E</exif:GPSLongitude>`,
+			&Location{Latitude: -33.86, Longitude: 151.21}},
+		{`exif:GPSLatitude="1,0N" exif:GPSLongitude="2,0E" exif:GPSAltitude="355/10"`,
+			&Location{Latitude: 1, Longitude: 2, Altitude: ptr(35.5)}},
+		{`exif:GPSLatitude="1,0N" exif:GPSLongitude="2,0E" exif:GPSAltitude="12" exif:GPSAltitudeRef="1"`,
+			&Location{Latitude: 1, Longitude: 2, Altitude: ptr(-12.0)}},
+		{`exif:GPSLatitude="1,0N" exif:GPSLongitude="2,0E" exif:GPSAltitude="1/0"`,
+			&Location{Latitude: 1, Longitude: 2}},
+		{`exif:GPSLatitude="51,30.5N"`, nil},
+		{`exif:GPSLatitude="51,30.5X" exif:GPSLongitude="0,7.5W"`, nil},
+		{`exif:GPSLatitude="91,0N" exif:GPSLongitude="0,7.5W"`, nil},
+		{`exif:GPSLatitude="1,2,3,4N" exif:GPSLongitude="0,7.5W"`, nil},
+		{`exif:GPSLatitude="a,bN" exif:GPSLongitude="0,7.5W"`, nil},
+		{`exif:GPSLatitude="N" exif:GPSLongitude="0,7.5W"`, nil},
+		{``, nil},
+	}
+	for _, test := range tests {
+		if got := parseXMPLocation([]byte(test.xmp)); !equalLocations(got, test.want) {
+			t.Errorf("parseXMPLocation(%q) = %s, want %s", test.xmp, formatLocation(got), formatLocation(test.want))
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	le := binary.LittleEndian
+	tiff := buildTIFF(le,
+		[]field{pointer(tagExifIFD, 1)},
+		[]field{ascii(tagDateTimeOriginal, "2019:05:17 13:14:15"), ascii(tagOffsetTimeOriginal, "-05:00")},
+	)
+	taken := time.Date(2019, 5, 17, 13, 14, 15, 0, time.FixedZone("", -5*60*60))
+	xmp := `http://ns.adobe.com/xap/1.0/` + "\x00" + `exif:GPSLatitude="1,0N" exif:GPSLongitude="2,0E"`
+	tiffWithGPS := buildTIFF(le, []field{pointer(tagGPSIFD, 1)}, london(le)[:4])
+
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		content  []byte
+		taken    *time.Time
+		location *Location
+	}{
+		{"exif.jpg", jpeg(segment(0xE0, []byte("JFIF\x00")), segment(0xE1, append([]byte("Exif\x00\x00"), tiff...))),
+			&taken, nil},
+		{"xmp.jpg", jpeg(segment(0xE1, append([]byte("Exif\x00\x00"), tiff...)), segment(0xE1, []byte(xmp))),
+			&taken, &Location{Latitude: 1, Longitude: 2}},
+		{"exif-gps.jpg", jpeg(segment(0xE1, []byte(xmp)), segment(0xE1, append([]byte("Exif\x00\x00"), tiffWithGPS...))),
+			nil, &Location{Latitude: 51 + 30.0/60 + 30.0/3600, Longitude: -0.125}},
+		{"plain.jpg", jpeg(segment(0xE0, []byte("JFIF\x00"))), nil, nil},
+		{"truncated.jpg", jpeg(segment(0xE1, append([]byte("Exif\x00\x00"), tiff...)))[:20], nil, nil},
+		{"raw.tif", tiff, &taken, nil},
+		{"text.txt", []byte("hello"), nil, nil},
+		{"empty", nil, nil, nil},
+	}
+	for _, test := range tests {
+		path := filepath.Join(dir, test.name)
+		if err := os.WriteFile(path, test.content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		m, err := Read(path)
+		if err != nil {
+			t.Errorf("Read(%s): %s", test.name, err)
+			continue
+		}
+		if !equalTimes(m.Taken, test.taken) {
+			t.Errorf("Read(%s): taken %v, want %v", test.name, m.Taken, test.taken)
+		}
+		if !equalLocations(m.Location, test.location) {
+			t.Errorf("Read(%s): location %s, want %s", test.name, formatLocation(m.Location), formatLocation(test.location))
+		}
+	}
+	if _, err := Read(filepath.Join(dir, "missing.jpg")); err == nil {
+		t.Error("Read of a missing file succeeded")
+	}
+}
+
+// jpeg returns a JPEG file with the given segments, but no image data.
+func jpeg(segments ...[]byte) []byte {
+	b := []byte{0xFF, 0xD8}
+	for _, s := range segments {
+		b = append(b, s...)
+	}
+	return append(b, 0xFF, 0xDA, 0x00, 0x02, 0xFF, 0xD9)
+}
+
+func segment(marker byte, data []byte) []byte {
+	b := []byte{0xFF, marker}
+	b = binary.BigEndian.AppendUint16(b, uint16(len(data)+2))
+	return append(b, data...)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func equalTimes(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	_, offsetA := a.Zone()
+	_, offsetB := b.Zone()
+	return a.Equal(*b) && offsetA == offsetB
+}
+
+func equalLocations(a, b *Location) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	const ε = 1e-9
+	if math.Abs(a.Latitude-b.Latitude) > ε || math.Abs(a.Longitude-b.Longitude) > ε {
+		return false
+	}
+	if a.Altitude == nil || b.Altitude == nil {
+		return a.Altitude == b.Altitude
+	}
+	return math.Abs(*a.Altitude-*b.Altitude) <= ε
+}
+
+func formatLocation(l *Location) string {
+	if l == nil {
+		return "nil"
+	}
+	if l.Altitude == nil {
+		return fmt.Sprintf("%g,%g", l.Latitude, l.Longitude)
+	}
+	return fmt.Sprintf("%g,%g,%g", l.Latitude, l.Longitude, *l.Altitude)
+}
@@ -0,0 +1,254 @@
+// Package ignore decides which files to ignore according to patterns
+// in the syntax of gitignore files. Patterns are read from .denignore
+// files in any directory and may additionally be given for a whole
+// tree.
+package ignore
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// FileName is the name of the files holding ignore patterns. Their
+// patterns apply to the directory they are in and everything below.
+const FileName = ".denignore"
+
+// Matcher decides which files below a root directory are ignored. The
+// .denignore files are read when they are first needed. It is safe for
+// concurrent use.
+type Matcher struct {
+	root   string
+	global []pattern
+
+	mu   sync.Mutex
+	dirs map[string][]pattern // The patterns of .denignore files.
+}
+
+type pattern struct {
+	re      *regexp.Regexp
+	negate  bool // Re-include matched files.
+	dirOnly bool
+}
+
+// New returns a Matcher for the files below root. The given patterns
+// apply to the whole tree, but the patterns of .denignore files take
+// precedence.
+func New(root string, patterns []string) (*Matcher, error) {
+	m := &Matcher{root: root, dirs: make(map[string][]pattern)}
+	for _, line := range patterns {
+		p, ok, err := parse(line)
+		if err != nil {
+			return nil, err
+		} else if ok {
+			m.global = append(m.global, p)
+		}
+	}
+	return m, nil
+}
+
+// Validate returns an error, if line is not a valid pattern.
+func Validate(line string) error {
+	_, _, err := parse(line)
+	return err
+}
+
+// Ignored reports whether the file at path, which must be below the
+// root of m, is ignored. Files within ignored directories are ignored
+// as well.
+func (m *Matcher) Ignored(path string, isDir bool) (bool, error) {
+	rel, err := filepath.Rel(m.root, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false, err
+	}
+	components := strings.Split(filepath.ToSlash(rel), "/")
+	for i := 1; i <= len(components); i++ {
+		ignored, err := m.matches(components[:i], i < len(components) || isDir)
+		if err != nil || ignored {
+			return ignored, err
+		}
+	}
+	return false, nil
+}
+
+// Forget drops the patterns read from the .denignore file in dir, so
+// that the file is read again, when it is needed next.
+func (m *Matcher) Forget(dir string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.dirs, dir)
+}
+
+// matches reports whether the file, whose path relative to the root is
+// given by components, is matched by the patterns. The last matching
+// pattern decides, where patterns of deeper .denignore files come
+// last.
+func (m *Matcher) matches(components []string, isDir bool) (bool, error) {
+	ignored := false
+	apply := func(patterns []pattern, rel string) {
+		for _, p := range patterns {
+			if (!p.dirOnly || isDir) && p.re.MatchString(rel) {
+				ignored = !p.negate
+			}
+		}
+	}
+	apply(m.global, strings.Join(components, "/"))
+	dir := m.root
+	for i := range components {
+		patterns, err := m.patternsOf(dir)
+		if err != nil {
+			return false, err
+		}
+		apply(patterns, strings.Join(components[i:], "/"))
+		dir = filepath.Join(dir, components[i])
+	}
+	return ignored, nil
+}
+
+func (m *Matcher) patternsOf(dir string) ([]pattern, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if patterns, ok := m.dirs[dir]; ok {
+		return patterns, nil
+	}
+	patterns, err := readFile(filepath.Join(dir, FileName))
+	if err != nil {
+		return nil, err
+	}
+	m.dirs[dir] = patterns
+	return patterns, nil
+}
+
+func readFile(path string) ([]pattern, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var patterns []pattern
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		p, ok, err := parse(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern in '%s': %s", path, err)
+		} else if ok {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns, scanner.Err()
+}
+
+// parse parses a line of a .denignore file. If the line holds no
+// pattern, false is returned.
+func parse(line string) (pattern, bool, error) {
+	line = strings.TrimSuffix(line, "\r")
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return pattern{}, false, nil
+	}
+	var p pattern
+	if rest, ok := strings.CutPrefix(line, "!"); ok {
+		p.negate, line = true, rest
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if rest, ok := strings.CutSuffix(line, "/"); ok {
+		p.dirOnly, line = true, rest
+	}
+	// Patterns with a slash are relative to the directory of the
+	// .denignore file, others match at any level.
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	if line == "" {
+		return pattern{}, false, nil
+	}
+	expr := "^"
+	if !anchored {
+		expr += "(?:.*/)?"
+	}
+	body, err := globToRegexp(line)
+	if err != nil {
+		return pattern{}, false, fmt.Errorf("invalid pattern '%s': %s", line, err)
+	}
+	if p.re, err = regexp.Compile(expr + body + "$"); err != nil {
+		return pattern{}, false, fmt.Errorf("invalid pattern '%s': %s", line, err)
+	}
+	return p, true, nil
+}
+
+// globToRegexp converts a glob, in which "**" matches any number of
+// directories, to a regular expression.
+func globToRegexp(glob string) (string, error) {
+	var expr strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/") && (i == 0 || glob[i-1] == '/'):
+			expr.WriteString("(?:.*/)?")
+			i += 2
+		case glob[i:] == "**" && (i == 0 || glob[i-1] == '/'):
+			expr.WriteString(".*")
+			i++
+		case c == '*':
+			expr.WriteString("[^/]*")
+		case c == '?':
+			expr.WriteString("[^/]")
+		case c == '\\' && i+1 < len(glob):
+			i++
+			expr.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		case c == '[':
+			class, n, err := globClass(glob[i+1:])
+			if err != nil {
+				return "", err
+			}
+			expr.WriteString(class)
+			i += n
+		default:
+			expr.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	return expr.String(), nil
+}
+
+// globClass converts the character class at the start of s, which
+// follows a [, to a regular expression. It also returns the length of
+// the class in s, including the closing ]. A ] right after [ or [! and
+// characters escaped with \ are matched literally.
+func globClass(s string) (string, int, error) {
+	var class strings.Builder
+	class.WriteString("[")
+	i := 0
+	if i < len(s) && (s[i] == '!' || s[i] == '^') {
+		class.WriteString("^")
+		i++
+	}
+	for first := true; i < len(s); first = false {
+		c := s[i]
+		switch {
+		case c == ']' && !first:
+			return class.String() + "]", i + 1, nil
+		case c == '\\' && i+1 < len(s):
+			r, size := utf8.DecodeRuneInString(s[i+1:])
+			fmt.Fprintf(&class, `\x{%x}`, r)
+			i += 1 + size
+			continue
+		case c == '\\' || c == '[' || c == ']':
+			class.WriteString(`\` + string(c))
+		default:
+			class.WriteByte(c)
+		}
+		i++
+	}
+	return "", 0, fmt.Errorf("unterminated character class")
+}
@@ -0,0 +1,254 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnored(t *testing.T) {
+	type check struct {
+		path  string
+		isDir bool
+		want  bool
+	}
+	tests := []struct {
+		name   string
+		global []string
+		files  map[string]string // Contents of .denignore files by directory.
+		checks []check
+	}{
+		{
+			name:   "unanchored",
+			global: []string{"*.log"},
+			checks: []check{
+				{"a.log", false, true},
+				{"sub/deep/a.log", false, true},
+				{"a.logs", false, false},
+				{"log", false, false},
+			},
+		},
+		{
+			name:   "anchored",
+			global: []string{"/top.txt", "sub/x"},
+			checks: []check{
+				{"top.txt", false, true},
+				{"sub/top.txt", false, false},
+				{"sub/x", false, true},
+				{"other/sub/x", false, false},
+			},
+		},
+		{
+			name:   "leading **",
+			global: []string{"**/logs"},
+			checks: []check{
+				{"logs", true, true},
+				{"a/logs", true, true},
+				{"a/b/logs", false, true},
+				{"a/b/logs/x", false, true},
+				{"a/catalogs", true, false},
+			},
+		},
+		{
+			name:   "trailing **",
+			global: []string{"cache/**"},
+			checks: []check{
+				{"cache", true, false},
+				{"cache/x", false, true},
+				{"cache/a/b", false, true},
+				{"sub/cache/x", false, false},
+			},
+		},
+		{
+			name:   "inner **",
+			global: []string{"a/**/b"},
+			checks: []check{
+				{"a/b", false, true},
+				{"a/x/b", false, true},
+				{"a/x/y/b", false, true},
+				{"a/xb", false, false},
+			},
+		},
+		{
+			name:   "** within a name",
+			global: []string{"foo**"},
+			checks: []check{
+				{"foobar", false, true},
+				{"sub/foobar", false, true},
+				{"foo/bar", true, true},
+				{"barfoo", false, false},
+			},
+		},
+		{
+			name:   "trailing spaces",
+			global: []string{"plain  ", `escaped\ `},
+			checks: []check{
+				{"plain", false, true},
+				{"plain ", false, false},
+				{"escaped ", false, true},
+				{"escaped", false, false},
+			},
+		},
+		{
+			name:   "comments and escapes",
+			global: []string{"# not a pattern", `\#hash`, `\!bang`},
+			checks: []check{
+				{"# not a pattern", false, false},
+				{"#hash", false, true},
+				{"!bang", false, true},
+			},
+		},
+		{
+			name:   "re-include",
+			global: []string{"*.log", "!keep.log"},
+			checks: []check{
+				{"a.log", false, true},
+				{"keep.log", false, false},
+				{"sub/keep.log", false, false},
+			},
+		},
+		{
+			name:   "re-include within ignored directory",
+			global: []string{"build/", "!build/keep"},
+			checks: []check{
+				{"build", true, true},
+				{"build/keep", false, true},
+			},
+		},
+		{
+			name:   "later patterns win",
+			global: []string{"!keep.log", "*.log"},
+			checks: []check{
+				{"keep.log", false, true},
+			},
+		},
+		{
+			name:   "directories only",
+			global: []string{"tmp/"},
+			checks: []check{
+				{"tmp", true, true},
+				{"tmp", false, false},
+				{"tmp/file", false, true},
+				{"sub/tmp", true, true},
+				{"sub/tmp/file", false, true},
+			},
+		},
+		{
+			name:   "character classes",
+			global: []string{"[!a]*.txt", "[a-c].md", `[\]x].csv`, "[]y]z", "[!]]q", `[\-]r`},
+			checks: []check{
+				{"a.txt", false, false},
+				{"b.txt", false, true},
+				{"b.md", false, true},
+				{"d.md", false, false},
+				{"x.csv", false, true},
+				{"y.csv", false, false},
+				{"]z", false, true},
+				{"yz", false, true},
+				{"xz", false, false},
+				{"aq", false, true},
+				{"]q", false, false},
+				{"-r", false, true},
+				{"\\r", false, false},
+			},
+		},
+		{
+			name:  ".denignore applies to its directory",
+			files: map[string]string{"sub": "/x\r\n*.tmp\r\n"},
+			checks: []check{
+				{"x", false, false},
+				{"sub/x", false, true},
+				{"sub/deep/x", false, false},
+				{"a.tmp", false, false},
+				{"sub/deep/a.tmp", false, true},
+			},
+		},
+		{
+			name:   ".denignore takes precedence over patterns",
+			global: []string{"*.jpg", "!a.tmp"},
+			files:  map[string]string{".": "!keep.jpg\n*.tmp\n"},
+			checks: []check{
+				{"a.jpg", false, true},
+				{"keep.jpg", false, false},
+				{"a.tmp", false, true},
+			},
+		},
+		{
+			name:  "deeper .denignore takes precedence",
+			files: map[string]string{".": "*.log\n", "sub": "!*.log\n"},
+			checks: []check{
+				{"a.log", false, true},
+				{"sub/a.log", false, false},
+				{"sub/deep/a.log", false, false},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := t.TempDir()
+			for dir, content := range test.files {
+				dir = filepath.Join(root, dir)
+				if err := os.MkdirAll(dir, 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(dir, FileName), []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			m, err := New(root, test.global)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, c := range test.checks {
+				got, err := m.Ignored(filepath.Join(root, c.path), c.isDir)
+				if err != nil {
+					t.Fatalf("Ignored(%q): %s", c.path, err)
+				}
+				if got != c.want {
+					t.Errorf("Ignored(%q, %t) = %t, want %t", c.path, c.isDir, got, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestForget(t *testing.T) {
+	root := t.TempDir()
+	m, err := New(root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(root, "a.tmp")
+	if ignored, _ := m.Ignored(path, false); ignored {
+		t.Fatal("ignored without patterns")
+	}
+	if err = os.WriteFile(filepath.Join(root, FileName), []byte("*.tmp\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if ignored, _ := m.Ignored(path, false); ignored {
+		t.Error("patterns were read again before Forget")
+	}
+	m.Forget(root)
+	if ignored, _ := m.Ignored(path, false); !ignored {
+		t.Error("patterns were not read again after Forget")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		line    string
+		wantErr bool
+	}{
+		{"*.log", false},
+		{"", false},
+		{"# comment", false},
+		{"[abc", true},
+		{"a/[!", true},
+		{"[z-a]", true},
+	}
+	for _, test := range tests {
+		if err := Validate(test.line); (err != nil) != test.wantErr {
+			t.Errorf("Validate(%q) = %v, want error: %t", test.line, err, test.wantErr)
+		}
+	}
+}
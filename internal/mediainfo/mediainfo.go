@@ -1,6 +1,8 @@
 package mediainfo
 
 import (
+	"math"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -12,14 +14,38 @@ type Info struct {
 	Type Type
 
 	// If Type is TypeVideo or TypeAudio, look at these fields:
-	Duration *time.Duration
-	Year     *int
+	Duration   *time.Duration
+	Year       *int
+	AudioCodec string
+	Bitrate    *int // In bits per second.
 
-	// If Type is TypeAudio, look at this field:
-	Author string
+	// If Type is TypeAudio, look at these fields:
+	Author, AlbumArtist string
+	Album, Title, Genre string
+	Track, Disc         *int
 
-	// If Type is TypeVideo or TypePicture, look at this field:
+	// If Type is TypeVideo or TypePicture, look at these fields:
 	Camera string
+
+	// Width and Height are given as displayed, so they are swapped for
+	// videos that are rotated by 90 degrees. The rotation of pictures is
+	// not considered.
+	Width, Height *int
+
+	// If Type is TypeVideo, look at these fields:
+	Taken      *time.Time
+	Location   *Location
+	FrameRate  *float64
+	VideoCodec string
+}
+
+// Location is a position in WGS 84 coordinates.
+type Location struct {
+	Latitude, Longitude float64
+
+	// Altitude is given in meters above sea level. It is nil if
+	// unknown.
+	Altitude *float64
 }
 
 type Type int
@@ -58,6 +84,25 @@ func MediaInfo(path string) (Info, error) {
 					i.Year = &y
 				}
 			}
+			i.Taken = getTaken(mi.Get(mediainfo.StreamGeneral, 0, "Inform"),
+				mi.Get(mediainfo.StreamGeneral, 0, "Format"), date,
+				mi.Get(mediainfo.StreamGeneral, 0, "Encoded_Date"))
+			i.Location = getLocation(mi.Get(mediainfo.StreamGeneral, 0, "Inform"),
+				mi.Get(mediainfo.StreamGeneral, 0, "Recorded_Location"))
+			i.Bitrate = getNumber(mi.Get(mediainfo.StreamGeneral, 0, "OverallBitRate"))
+		}
+		i.Width = getNumber(mi.Get(mediainfo.StreamVideo, 0, "Width"))
+		i.Height = getNumber(mi.Get(mediainfo.StreamVideo, 0, "Height"))
+		rotation, _ := strconv.ParseFloat(mi.Get(mediainfo.StreamVideo, 0, "Rotation"), 64)
+		if r := math.Mod(math.Abs(rotation), 180); r > 45 && r < 135 {
+			i.Width, i.Height = i.Height, i.Width
+		}
+		if fps, err := strconv.ParseFloat(mi.Get(mediainfo.StreamVideo, 0, "FrameRate"), 64); err == nil {
+			i.FrameRate = &fps
+		}
+		i.VideoCodec = mi.Get(mediainfo.StreamVideo, 0, "Format")
+		if mi.Count(mediainfo.StreamAudio) > 0 {
+			i.AudioCodec = mi.Get(mediainfo.StreamAudio, 0, "Format")
 		}
 	} else if mi.Count(mediainfo.StreamAudio) > 0 {
 		i.Type = TypeAudio
@@ -71,13 +116,25 @@ func MediaInfo(path string) (Info, error) {
 				}
 			}
 			i.Author = mi.Get(mediainfo.StreamGeneral, 0, "Performer")
+			i.AlbumArtist = mi.Get(mediainfo.StreamGeneral, 0, "Album/Performer")
+			i.Album = mi.Get(mediainfo.StreamGeneral, 0, "Album")
+			i.Title = mi.Get(mediainfo.StreamGeneral, 0, "Title")
+			if i.Title == "" {
+				// MediaInfo calls the title of a song "Track":
+				i.Title = mi.Get(mediainfo.StreamGeneral, 0, "Track")
+			}
+			i.Genre = mi.Get(mediainfo.StreamGeneral, 0, "Genre")
+			i.Track = getNumber(mi.Get(mediainfo.StreamGeneral, 0, "Track/Position"))
+			i.Disc = getNumber(mi.Get(mediainfo.StreamGeneral, 0, "Part/Position"))
 			date := mi.Get(mediainfo.StreamGeneral, 0, "Recorded_Date")
 			if len(date) >= 4 {
 				if y, err := strconv.Atoi(date[:4]); err == nil {
 					i.Year = &y
 				}
 			}
+			i.Bitrate = getNumber(mi.Get(mediainfo.StreamGeneral, 0, "OverallBitRate"))
 		}
+		i.AudioCodec = mi.Get(mediainfo.StreamAudio, 0, "Format")
 	} else if mi.Count(mediainfo.StreamImage) > 0 {
 		i.Type = TypePicture
 		if mi.Count(mediainfo.StreamGeneral) > 0 {
@@ -87,11 +144,94 @@ func MediaInfo(path string) (Info, error) {
 				i.Camera = getCamera(mi.Get(mediainfo.StreamGeneral, 0, "Inform"))
 			}
 		}
+		i.Width = getNumber(mi.Get(mediainfo.StreamImage, 0, "Width"))
+		i.Height = getNumber(mi.Get(mediainfo.StreamImage, 0, "Height"))
 	}
 	defer mi.Close()
 	return i, nil
 }
 
+// getNumber parses values like "3" or "3/12" and returns nil, if the
+// value is empty or invalid.
+func getNumber(val string) *int {
+	val, _, _ = strings.Cut(val, "/")
+	n, err := strconv.Atoi(strings.TrimSpace(val))
+	if err != nil {
+		return nil
+	}
+	return &n
+}
+
+// getTaken determines the time a video was recorded. The creation date
+// of Apple devices is preferred, because it includes the time zone
+// offset. The encoding date is only used for QuickTime files, where
+// cameras set it when recording, and is given in UTC. Other containers,
+// like Matroska, store the time the file was written there.
+func getTaken(informVal, format, recorded, encoded string) *time.Time {
+	for line := range strings.Lines(informVal) {
+		s := strings.SplitN(line, ":", 2)
+		if len(s) == 2 && strings.TrimSpace(s[0]) == "COM.APPLE.QUICKTIME.CREATIONDATE" {
+			t, err := time.Parse("2006-01-02T15:04:05-0700", strings.TrimSpace(s[1]))
+			if err == nil {
+				return &t
+			}
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, recorded); err == nil {
+		return &t
+	}
+	if format != "MPEG-4" && format != "QuickTime" {
+		return nil
+	}
+	encoded = strings.TrimSpace(strings.ReplaceAll(encoded, "UTC", ""))
+	t, err := time.Parse(time.DateTime, encoded)
+	if err != nil || t.Year() <= 1904 {
+		// QuickTime files without a date use the start of its epoch.
+		return nil
+	}
+	return &t
+}
+
+// getLocation determines the location a video was recorded at. The
+// location is given in the ISO 6709 notation, e.g.
+// "+37.3349-122.0090+030.000/", either by Apple devices or in the
+// "©xyz" atom of QuickTime files.
+func getLocation(informVal, recorded string) *Location {
+	for line := range strings.Lines(informVal) {
+		s := strings.SplitN(line, ":", 2)
+		if len(s) == 2 && strings.TrimSpace(s[0]) == "COM.APPLE.QUICKTIME.LOCATION.ISO6709" {
+			if l := parseISO6709(strings.TrimSpace(s[1])); l != nil {
+				return l
+			}
+		}
+	}
+	return parseISO6709(strings.TrimSpace(recorded))
+}
+
+var iso6709 = regexp.MustCompile(`^([+-][0-9.]+)([+-][0-9.]+)([+-][0-9.]+)?/?$`)
+
+// parseISO6709 parses coordinates given in decimal degrees, followed
+// by an optional altitude. It returns nil for other notations.
+func parseISO6709(s string) *Location {
+	match := iso6709.FindStringSubmatch(s)
+	if match == nil {
+		return nil
+	}
+	lat, err := strconv.ParseFloat(match[1], 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return nil
+	}
+	lon, err := strconv.ParseFloat(match[2], 64)
+	if err != nil || lon < -180 || lon > 180 {
+		return nil
+	}
+	l := &Location{Latitude: lat, Longitude: lon}
+	if alt, err := strconv.ParseFloat(match[3], 64); err == nil {
+		l.Altitude = &alt
+	}
+	return l
+}
+
 // getCamera tries to find the camera inside manufacturer specific
 // fields of the "Inform" value.
 func getCamera(informVal string) string {
@@ -0,0 +1,64 @@
+package mediainfo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetTaken(t *testing.T) {
+	apple := "General\nCOM.APPLE.QUICKTIME.CREATIONDATE : 2019-05-17T13:14:15+0200\n"
+	tests := []struct {
+		name                              string
+		inform, format, recorded, encoded string
+		want                              *time.Time
+	}{
+		{"apple", apple, "MPEG-4", "", "2019-05-17 11:14:15 UTC",
+			ptr(time.Date(2019, 5, 17, 13, 14, 15, 0, time.FixedZone("", 2*60*60)))},
+		{"recorded", "", "Matroska", "2019-05-17T13:14:15Z", "2023-01-01 00:00:00 UTC",
+			ptr(time.Date(2019, 5, 17, 13, 14, 15, 0, time.UTC))},
+		{"MPEG-4", "", "MPEG-4", "", "UTC 2019-05-17 11:14:15",
+			ptr(time.Date(2019, 5, 17, 11, 14, 15, 0, time.UTC))},
+		{"QuickTime", "", "QuickTime", "", "2019-05-17 11:14:15 UTC",
+			ptr(time.Date(2019, 5, 17, 11, 14, 15, 0, time.UTC))},
+		{"QuickTime epoch", "", "MPEG-4", "", "1904-01-01 00:00:00 UTC", nil},
+		{"Matroska", "", "Matroska", "", "2023-01-01 00:00:00 UTC", nil},
+		{"WebM", "", "WebM", "", "2023-01-01 00:00:00 UTC", nil},
+		{"AVI", "", "AVI", "", "2023-01-01 00:00:00", nil},
+		{"unknown", "", "MPEG-4", "", "", nil},
+	}
+	for _, test := range tests {
+		got := getTaken(test.inform, test.format, test.recorded, test.encoded)
+		if (got == nil) != (test.want == nil) || got != nil && !got.Equal(*test.want) {
+			t.Errorf("%s: taken %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestParseISO6709(t *testing.T) {
+	tests := []struct {
+		s    string
+		want *Location
+	}{
+		{"+37.3349-122.0090+030.000/", &Location{37.3349, -122.009, ptr(30.0)}},
+		{"-33.86+151.21/", &Location{-33.86, 151.21, nil}},
+		{"+37.3349-122.0090", &Location{37.3349, -122.009, nil}},
+		{"+91.0+0.0/", nil},
+		{"+0.0-181.0/", nil},
+		{"+40°26'46\"N", nil},
+		{"", nil},
+	}
+	for _, test := range tests {
+		got := parseISO6709(test.s)
+		if (got == nil) != (test.want == nil) {
+			t.Errorf("parseISO6709(%q) = %v, want %v", test.s, got, test.want)
+		} else if got != nil && (got.Latitude != test.want.Latitude || got.Longitude != test.want.Longitude ||
+			(got.Altitude == nil) != (test.want.Altitude == nil) ||
+			got.Altitude != nil && *got.Altitude != *test.want.Altitude) {
+			t.Errorf("parseISO6709(%q) = %+v, want %+v", test.s, *got, *test.want)
+		}
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
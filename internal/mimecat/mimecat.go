@@ -5,6 +5,8 @@
 //   - Video
 //   - Audio
 //   - Document
+//   - Archive
+//   - Executable
 package mimecat
 
 import "strings"
@@ -17,6 +19,8 @@ const (
 	Video
 	Audio
 	Document
+	Archive
+	Executable
 )
 
 func MIMEToCategory(mime string) Category {
@@ -27,7 +31,7 @@ func MIMEToCategory(mime string) Category {
 		"application/msword",
 		"application/pdf",
 		"application/rtf",
-		"application/vnd.oasis.opendocument.presentation ",
+		"application/vnd.oasis.opendocument.presentation",
 		"application/vnd.oasis.opendocument.spreadsheet",
 		"application/vnd.oasis.opendocument.text",
 		"application/vnd.openxmlformats-officedocument.presentationml.presentation",
@@ -42,6 +46,19 @@ func MIMEToCategory(mime string) Category {
 		"application/xml",
 		"application/yaml":
 		return Document
+	case "application/gzip",
+		"application/vnd.rar",
+		"application/x-7z-compressed",
+		"application/x-bzip2",
+		"application/x-gzip",
+		"application/x-rar-compressed",
+		"application/x-tar",
+		"application/x-xz",
+		"application/zip",
+		"application/zstd":
+		return Archive
+	case "application/x-executable":
+		return Executable
 	}
 	firstSegment, _, _ := strings.Cut(mime, "/")
 	switch firstSegment {
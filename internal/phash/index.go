@@ -0,0 +1,73 @@
+package phash
+
+import "iter"
+
+// Index finds the hashes, that differ from a given hash in few bits,
+// without comparing it to all hashes. It is a BK-tree: the children of
+// a node are keyed by their distance to it, so that, by the triangle
+// inequality, only the children within threshold of the distance
+// between a node and the searched hash need to be visited.
+//
+// The zero value is an empty index.
+type Index struct {
+	root *node
+}
+
+type node struct {
+	hash     uint64
+	ids      []int // All IDs added with hash.
+	children map[int]*node
+}
+
+// Add adds the hash with the given ID to the index.
+func (x *Index) Add(hash uint64, id int) {
+	if x.root == nil {
+		x.root = &node{hash: hash, ids: []int{id}}
+		return
+	}
+	n := x.root
+	for {
+		d := Distance(n.hash, hash)
+		if d == 0 {
+			n.ids = append(n.ids, id)
+			return
+		}
+		child, ok := n.children[d]
+		if !ok {
+			if n.children == nil {
+				n.children = make(map[int]*node)
+			}
+			n.children[d] = &node{hash: hash, ids: []int{id}}
+			return
+		}
+		n = child
+	}
+}
+
+// Near returns the IDs of all hashes, that differ from hash in at most
+// threshold bits, in no particular order.
+func (x *Index) Near(hash uint64, threshold int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		if x.root == nil {
+			return
+		}
+		stack := []*node{x.root}
+		for len(stack) > 0 {
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			d := Distance(n.hash, hash)
+			if d <= threshold {
+				for _, id := range n.ids {
+					if !yield(id) {
+						return
+					}
+				}
+			}
+			for cd, child := range n.children {
+				if cd >= d-threshold && cd <= d+threshold {
+					stack = append(stack, child)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package phash
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestIndexNear(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	var hashes []uint64
+	for range 200 {
+		hashes = append(hashes, r.Uint64())
+	}
+	// Add hashes, that differ from others in few bits, and duplicates:
+	for i := range 300 {
+		h := hashes[r.IntN(len(hashes))]
+		for range i % 6 {
+			h ^= 1 << r.IntN(64)
+		}
+		hashes = append(hashes, h)
+	}
+
+	var x Index
+	for i, h := range hashes {
+		x.Add(h, i)
+	}
+	for _, threshold := range []int{0, 1, 3, 10, 64} {
+		for i, h := range hashes {
+			var want []int
+			for j, other := range hashes {
+				if Distance(h, other) <= threshold {
+					want = append(want, j)
+				}
+			}
+			got := slices.Sorted(x.Near(h, threshold))
+			if !slices.Equal(got, want) {
+				t.Fatalf("Near(hashes[%d], %d) = %v, want %v", i, threshold, got, want)
+			}
+		}
+	}
+}
+
+func TestIndexEmpty(t *testing.T) {
+	var x Index
+	for id := range x.Near(0, 64) {
+		t.Errorf("empty index returned %d", id)
+	}
+}
@@ -0,0 +1,73 @@
+// Package phash computes perceptual hashes of pictures. Pictures that
+// look alike have hashes that differ in few bits, even if they were
+// resized, recompressed or re-exported. Supported are JPEG, PNG and GIF
+// files.
+package phash
+
+import (
+	"bufio"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"math/bits"
+	"os"
+)
+
+// samples is the number of pixels per row and column of a cell of the
+// hash grid, that are considered. Looking at a sample of the pixels is
+// much faster than looking at all of them and good enough for
+// thumbnail-sized input.
+const samples = 8
+
+// DHash returns the difference hash of the picture at path. The picture
+// is shrunk to 9x8 gray pixels and a bit is set for each pixel, that is
+// brighter than its right neighbour.
+func DHash(path string) (uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(bufio.NewReader(f))
+	if err != nil {
+		return 0, err
+	}
+	return dHash(img), nil
+}
+
+// Distance returns the number of bits in which the hashes a and b
+// differ.
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+func dHash(img image.Image) uint64 {
+	const width, height = 9, 8
+	var gray [height][width]float64
+	bounds := img.Bounds()
+	for row := range height {
+		for col := range width {
+			var sum float64
+			for sy := range samples {
+				y := bounds.Min.Y + (row*samples+sy)*bounds.Dy()/(height*samples)
+				for sx := range samples {
+					x := bounds.Min.X + (col*samples+sx)*bounds.Dx()/(width*samples)
+					r, g, b, _ := img.At(x, y).RGBA()
+					sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+				}
+			}
+			gray[row][col] = sum
+		}
+	}
+	var hash uint64
+	for row := range height {
+		for col := range width - 1 {
+			hash <<= 1
+			if gray[row][col] > gray[row][col+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
@@ -0,0 +1,288 @@
+// Package querylang parses query expressions, which select files by
+// their metadata, e.g.
+//
+//	(camera:Canon OR camera:iPhone) AND NOT path:~/Pictures/scans
+//
+// Comparisons consist of a field, an operator and a value. The
+// operators are ":", "=", "!=", "<", "<=", ">", ">=" and "in", where
+// "in" expects a range like "2019..2021". Comparisons are combined with
+// AND, OR, NOT and parentheses; AND may be omitted. Values containing
+// spaces, parentheses or operators must be enclosed in double quotes.
+//
+// The fields and the meaning of the operators are not defined here,
+// but by the user of the parsed expression.
+package querylang
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Expr is a parsed expression. It is one of *And, *Or, *Not and
+// *Comparison.
+type Expr interface {
+	// Pos returns the offset of the expression within the parsed
+	// string.
+	Pos() int
+}
+
+type And struct{ Left, Right Expr }
+
+type Or struct{ Left, Right Expr }
+
+type Not struct {
+	X   Expr
+	pos int
+}
+
+type Comparison struct {
+	Field string
+	Op    string
+	Value string
+
+	// FieldPos and ValuePos are the offsets of the field and value
+	// within the parsed string.
+	FieldPos, ValuePos int
+}
+
+func (e *And) Pos() int        { return e.Left.Pos() }
+func (e *Or) Pos() int         { return e.Left.Pos() }
+func (e *Not) Pos() int        { return e.pos }
+func (e *Comparison) Pos() int { return e.FieldPos }
+
+// Error is an error at a position of a query expression.
+type Error struct {
+	// Pos is the offset of the erroneous part within the expression.
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Pos+1, e.Msg)
+}
+
+// Errorf returns an *Error at pos.
+func Errorf(pos int, format string, args ...any) error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenOp
+	tokenOpen
+	tokenClose
+)
+
+type token struct {
+	kind tokenKind
+	text string // The unquoted text of strings.
+	pos  int
+}
+
+// operators holds the operators, longer ones first.
+var operators = []string{"!=", "<=", ">=", ":", "=", "<", ">"}
+
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenOpen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenClose, text: ")", pos: i})
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				return nil, Errorf(i, "unterminated string")
+			}
+			text, err := strconv.Unquote(s[i : end+1])
+			if err != nil {
+				return nil, Errorf(i, "invalid string %s", s[i:end+1])
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text, pos: i})
+			i = end + 1
+		case isOperatorStart(s[i:]):
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(s[i:], o) {
+					op = o
+					break
+				}
+			}
+			tokens = append(tokens, token{kind: tokenOp, text: op, pos: i})
+			i += len(op)
+		default:
+			end := i
+			for end < len(s) && !strings.ContainsRune(" \t\n()\"", rune(s[end])) &&
+				!isOperatorStart(s[end:]) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: s[i:end], pos: i})
+			i = end
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(s)}), nil
+}
+
+func isOperatorStart(s string) bool {
+	for _, o := range operators {
+		if strings.HasPrefix(s, o) {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse parses the query expression s. The returned error is an *Error.
+func Parse(s string) (Expr, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := parser{tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return nil, Errorf(0, "empty expression")
+	}
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind == tokenClose {
+		return nil, Errorf(t.pos, "unexpected ')'")
+	} else if t.kind != tokenEOF {
+		return nil, Errorf(t.pos, "expected AND, OR or end of expression, got %s", describe(t))
+	}
+	return e, nil
+}
+
+type parser struct {
+	tokens []token
+	i      int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokenEOF {
+		p.i++
+	}
+	return t
+}
+
+// isKeyword reports whether t is the given keyword. Keywords are
+// case-insensitive and can be used as values by quoting them.
+func isKeyword(t token, keyword string) bool {
+	return t.kind == tokenWord && strings.EqualFold(t.text, keyword)
+}
+
+func (p *parser) or() (Expr, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for isKeyword(p.peek(), "or") {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = &Or{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (Expr, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if isKeyword(t, "and") {
+			p.next()
+		} else if t.kind == tokenEOF || t.kind == tokenClose || isKeyword(t, "or") {
+			return left, nil
+		}
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = &And{left, right}
+	}
+}
+
+func (p *parser) unary() (Expr, error) {
+	t := p.peek()
+	switch {
+	case isKeyword(t, "not"):
+		p.next()
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{X: x, pos: t.pos}, nil
+	case t.kind == tokenOpen:
+		p.next()
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if c := p.next(); c.kind != tokenClose {
+			return nil, Errorf(c.pos, "expected ')' to close '(' at column %d, got %s", t.pos+1, describe(c))
+		}
+		return e, nil
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() (Expr, error) {
+	field := p.next()
+	if field.kind != tokenWord || isKeyword(field, "and") || isKeyword(field, "or") {
+		return nil, Errorf(field.pos, "expected a comparison like 'camera:Canon', got %s", describe(field))
+	}
+	c := &Comparison{Field: strings.ToLower(field.text), FieldPos: field.pos}
+	op := p.next()
+	switch {
+	case op.kind == tokenOp:
+		c.Op = op.text
+	case isKeyword(op, "in"):
+		c.Op = "in"
+	default:
+		return nil, Errorf(op.pos, "expected an operator after '%s', got %s", field.text, describe(op))
+	}
+	value := p.next()
+	if value.kind != tokenWord && value.kind != tokenString {
+		return nil, Errorf(value.pos, "expected a value after '%s', got %s", field.text+op.text, describe(value))
+	}
+	c.Value, c.ValuePos = value.text, value.pos
+	return c, nil
+}
+
+func describe(t token) string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of expression"
+	case tokenString:
+		return strconv.Quote(t.text)
+	}
+	return "'" + t.text + "'"
+}
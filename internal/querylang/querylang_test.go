@@ -0,0 +1,134 @@
+package querylang
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// format returns e in a prefix notation, that shows its structure.
+func format(e Expr) string {
+	switch e := e.(type) {
+	case *And:
+		return "(AND " + format(e.Left) + " " + format(e.Right) + ")"
+	case *Or:
+		return "(OR " + format(e.Left) + " " + format(e.Right) + ")"
+	case *Not:
+		return "(NOT " + format(e.X) + ")"
+	case *Comparison:
+		return fmt.Sprintf("[%s %s %q]", e.Field, e.Op, e.Value)
+	}
+	return fmt.Sprintf("%T", e)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr, want string
+	}{
+		{`camera:Canon`, `[camera : "Canon"]`},
+		{`Camera:Canon`, `[camera : "Canon"]`},
+		{`path:~/Pictures/scans`, `[path : "~/Pictures/scans"]`},
+		{`size>=10M`, `[size >= "10M"]`},
+		{`size<=10M`, `[size <= "10M"]`},
+		{`size>1`, `[size > "1"]`},
+		{`size<1`, `[size < "1"]`},
+		{`size=1`, `[size = "1"]`},
+		{`size!=1`, `[size != "1"]`},
+		{`year in 2019..2021`, `[year in "2019..2021"]`},
+		{`year IN 2019..`, `[year in "2019.."]`},
+		{`album="Live at Pompeii"`, `[album = "Live at Pompeii"]`},
+		{`title:"say \"hi\""`, `[title : "say \"hi\""]`},
+		{`title:"OR"`, `[title : "OR"]`},
+		{`title:"a(b)"`, `[title : "a(b)"]`},
+		{`a:1 b:2`, `(AND [a : "1"] [b : "2"])`},
+		{`a:1 AND b:2`, `(AND [a : "1"] [b : "2"])`},
+		{`a:1 and b:2`, `(AND [a : "1"] [b : "2"])`},
+		{`a:1 b:2 c:3`, `(AND (AND [a : "1"] [b : "2"]) [c : "3"])`},
+		{`a:1 OR b:2 OR c:3`, `(OR (OR [a : "1"] [b : "2"]) [c : "3"])`},
+		{`a:1 or b:2`, `(OR [a : "1"] [b : "2"])`},
+		{`a:1 OR b:2 c:3`, `(OR [a : "1"] (AND [b : "2"] [c : "3"]))`},
+		{`a:1 b:2 OR c:3`, `(OR (AND [a : "1"] [b : "2"]) [c : "3"])`},
+		{`a:1 AND b:2 OR c:3 AND d:4`, `(OR (AND [a : "1"] [b : "2"]) (AND [c : "3"] [d : "4"]))`},
+		{`(a:1 OR b:2) c:3`, `(AND (OR [a : "1"] [b : "2"]) [c : "3"])`},
+		{`a:1 (b:2 OR c:3)`, `(AND [a : "1"] (OR [b : "2"] [c : "3"]))`},
+		{`NOT a:1 b:2`, `(AND (NOT [a : "1"]) [b : "2"])`},
+		{`not a:1 OR b:2`, `(OR (NOT [a : "1"]) [b : "2"])`},
+		{`NOT NOT a:1`, `(NOT (NOT [a : "1"]))`},
+		{`NOT (a:1 OR b:2)`, `(NOT (OR [a : "1"] [b : "2"]))`},
+		{"\ta:1\n\tb:2 ", `(AND [a : "1"] [b : "2"])`},
+		{`((a:1))`, `[a : "1"]`},
+	}
+	for _, test := range tests {
+		e, err := Parse(test.expr)
+		if err != nil {
+			t.Errorf("Parse(%q): %s", test.expr, err)
+		} else if got := format(e); got != test.want {
+			t.Errorf("Parse(%q) = %s, want %s", test.expr, got, test.want)
+		}
+	}
+}
+
+func TestParsePositions(t *testing.T) {
+	e, err := Parse(`NOT a:1 album:"x y"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	and := e.(*And)
+	if pos := and.Pos(); pos != 0 {
+		t.Errorf("position of AND is %d, want 0", pos)
+	}
+	not := and.Left.(*Not)
+	if pos := not.X.Pos(); pos != 4 {
+		t.Errorf("position of the negated comparison is %d, want 4", pos)
+	}
+	c := and.Right.(*Comparison)
+	if c.FieldPos != 8 || c.ValuePos != 14 {
+		t.Errorf("positions of field and value are %d and %d, want 8 and 14", c.FieldPos, c.ValuePos)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		pos  int
+		msg  string
+	}{
+		{``, 0, "empty expression"},
+		{`   `, 0, "empty expression"},
+		{`a`, 1, "expected an operator after 'a', got end of expression"},
+		{`a b:1`, 2, "expected an operator after 'a', got 'b'"},
+		{`a:`, 2, "expected a value after 'a:', got end of expression"},
+		{`a:(`, 2, "expected a value after 'a:', got '('"},
+		{`a:=1`, 2, "expected a value after 'a:', got '='"},
+		{`:x`, 0, "expected a comparison like 'camera:Canon', got ':'"},
+		{`AND a:1`, 0, "expected a comparison like 'camera:Canon', got 'AND'"},
+		{`a:1 OR`, 6, "expected a comparison like 'camera:Canon', got end of expression"},
+		{`a:1 OR OR b:2`, 7, "got 'OR'"},
+		{`NOT`, 3, "got end of expression"},
+		{`a:1)`, 3, "unexpected ')'"},
+		{`(a:1`, 4, "expected ')' to close '(' at column 1, got end of expression"},
+		{`a:1 (b:2 c:3`, 12, "expected ')' to close '(' at column 5"},
+		{`a:1 ()`, 5, "got ')'"},
+		{`a:"x`, 2, "unterminated string"},
+		{`a:"x\"`, 2, "unterminated string"},
+		{`a:"\q"`, 2, `invalid string "\q"`},
+		{`album:"x" "y"`, 10, `expected a comparison like 'camera:Canon', got "y"`},
+		{`ä:1 )`, 5, "unexpected ')'"},
+	}
+	for _, test := range tests {
+		_, err := Parse(test.expr)
+		var exprErr *Error
+		if !errors.As(err, &exprErr) {
+			t.Errorf("Parse(%q) returned %v, want *Error", test.expr, err)
+			continue
+		}
+		if exprErr.Pos != test.pos || !strings.Contains(exprErr.Msg, test.msg) {
+			t.Errorf("Parse(%q) failed at %d with %q, want %d and %q",
+				test.expr, exprErr.Pos, exprErr.Msg, test.pos, test.msg)
+		}
+		if want := fmt.Sprintf("column %d: %s", test.pos+1, exprErr.Msg); err.Error() != want {
+			t.Errorf("Parse(%q) returned error %q, want %q", test.expr, err, want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package units
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// yearRange matches the ranges of years accepted by -c before ranges
+// of dates were supported, e.g. 1990-1999.
+var yearRange = regexp.MustCompile(`^(\d{4})-(\d{4})$`)
+
+// ParseDateRange parses a date like 2019, 2019-05, 2019-05-17 or one of
+// the relative dates accepted by parseDate. Also accepted are ranges of
+// two dates separated by "..", where either date may be omitted, ranges
+// of years like 1990-1999 and "since DATE". The returned times are the
+// start of the first and the end of the last date; they are nil for
+// open ends.
+func ParseDateRange(s string) (*time.Time, *time.Time, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	var from, to string
+	if s == "" {
+		return nil, nil, fmt.Errorf("empty date")
+	} else if m := yearRange.FindStringSubmatch(s); m != nil {
+		from, to = m[1], m[2]
+	} else if date, ok := strings.CutPrefix(s, "since "); ok {
+		from = strings.TrimSpace(date)
+	} else if a, b, ok := strings.Cut(s, ".."); ok {
+		from, to = a, b
+		if from == "" && to == "" {
+			return nil, nil, fmt.Errorf("invalid date range '%s'", s)
+		}
+	} else {
+		from, to = s, s
+	}
+	var since, until *time.Time
+	if from != "" {
+		start, _, err := parseDate(from)
+		if err != nil {
+			return nil, nil, err
+		}
+		since = &start
+	}
+	if to != "" {
+		_, end, err := parseDate(to)
+		if err != nil {
+			return nil, nil, err
+		}
+		until = &end
+	}
+	return since, until, nil
+}
+
+// relativeDate matches durations like 12h, 7d, 2w, 3mo or 1y.
+var relativeDate = regexp.MustCompile(`^(\d+)(h|d|w|mo|y)$`)
+
+// parseDate returns the start and end of the year, month or day given
+// in s. s may also be "today", "yesterday", a weekday, which refers to
+// the last such day, or a duration like 7d, which refers to the time
+// from then until now.
+func parseDate(s string) (time.Time, time.Time, error) {
+	for _, layout := range []string{"2006", "2006-01", "2006-01-02"} {
+		start, err := time.ParseInLocation(layout, s, time.Local)
+		if err != nil {
+			continue
+		}
+		var end time.Time
+		switch layout {
+		case "2006":
+			end = start.AddDate(1, 0, 0)
+		case "2006-01":
+			end = start.AddDate(0, 1, 0)
+		default:
+			end = start.AddDate(0, 0, 1)
+		}
+		return start, end.Add(-time.Nanosecond), nil
+	}
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	day := func(daysAgo int) (time.Time, time.Time, error) {
+		start := today.AddDate(0, 0, -daysAgo)
+		return start, start.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+	}
+	switch s {
+	case "today":
+		return day(0)
+	case "yesterday":
+		return day(1)
+	}
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if s == strings.ToLower(weekday.String()) {
+			return day((int(today.Weekday()) - int(weekday) + 7) % 7)
+		}
+	}
+	if m := relativeDate.FindStringSubmatch(s); m != nil {
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid date '%s'", s)
+		}
+		var start time.Time
+		switch m[2] {
+		case "h":
+			start = now.Add(-time.Duration(n) * time.Hour)
+		case "d":
+			start = now.AddDate(0, 0, -n)
+		case "w":
+			start = now.AddDate(0, 0, -7*n)
+		case "mo":
+			start = now.AddDate(0, -n, 0)
+		case "y":
+			start = now.AddDate(-n, 0, 0)
+		}
+		return start, now, nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("invalid date '%s'", s)
+}
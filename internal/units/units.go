@@ -0,0 +1,54 @@
+// Package units parses the sizes, resolutions and dates accepted on the
+// command line and in query expressions.
+package units
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseResolution parses resolutions like 1080p, 4k or 720 and returns
+// the length of the shorter side in pixels.
+func ParseResolution(s string) (int, error) {
+	s = strings.ToLower(s)
+	switch s {
+	case "4k":
+		s = "2160"
+	case "8k":
+		s = "4320"
+	}
+	r, err := strconv.Atoi(strings.TrimSuffix(s, "p"))
+	if err != nil || r < 0 {
+		return 0, fmt.Errorf("invalid resolution '%s'", s)
+	}
+	return r, nil
+}
+
+// sizeUnits maps the units accepted by ParseSize to their number of
+// bytes. Units are matched case-insensitively.
+var sizeUnits = map[string]float64{
+	"": 1, "b": 1,
+	"k": 1e3, "kb": 1e3, "kib": 1 << 10,
+	"m": 1e6, "mb": 1e6, "mib": 1 << 20,
+	"g": 1e9, "gb": 1e9, "gib": 1 << 30,
+	"t": 1e12, "tb": 1e12, "tib": 1 << 40,
+}
+
+// ParseSize parses sizes like 500, 10M, 1.5G or 2GiB and returns the
+// number of bytes. K, M, G and T are powers of 1000, while KiB, MiB, GiB
+// and TiB are powers of 1024.
+func ParseSize(s string) (int64, error) {
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i == -1 {
+		i = len(s)
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	unit, ok := sizeUnits[strings.ToLower(s[i:])]
+	if err != nil || !ok {
+		return 0, fmt.Errorf("invalid size '%s'", s)
+	}
+	return int64(n * unit), nil
+}
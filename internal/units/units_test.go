@@ -0,0 +1,173 @@
+package units
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int64
+	}{
+		{"0", 0},
+		{"500", 500},
+		{"500B", 500},
+		{"10k", 10_000},
+		{"10K", 10_000},
+		{"10KB", 10_000},
+		{"10KiB", 10_240},
+		{"1.5G", 1_500_000_000},
+		{"2GiB", 2 << 30},
+		{"1tb", 1_000_000_000_000},
+		{"3MiB", 3 << 20},
+		{".5M", 500_000},
+	}
+	for _, test := range tests {
+		got, err := ParseSize(test.s)
+		if err != nil {
+			t.Errorf("ParseSize(%q): %s", test.s, err)
+		} else if got != test.want {
+			t.Errorf("ParseSize(%q) = %d, want %d", test.s, got, test.want)
+		}
+	}
+	for _, s := range []string{"", "M", "10X", "10 M", "-1", "1.2.3K", "10MM"} {
+		if got, err := ParseSize(s); err == nil {
+			t.Errorf("ParseSize(%q) = %d, want error", s, got)
+		}
+	}
+}
+
+func TestParseResolution(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"720", 720},
+		{"1080p", 1080},
+		{"1080P", 1080},
+		{"4k", 2160},
+		{"4K", 2160},
+		{"8k", 4320},
+	}
+	for _, test := range tests {
+		got, err := ParseResolution(test.s)
+		if err != nil {
+			t.Errorf("ParseResolution(%q): %s", test.s, err)
+		} else if got != test.want {
+			t.Errorf("ParseResolution(%q) = %d, want %d", test.s, got, test.want)
+		}
+	}
+	for _, s := range []string{"", "p", "hd", "-720", "2k", "10.5p"} {
+		if got, err := ParseResolution(s); err == nil {
+			t.Errorf("ParseResolution(%q) = %d, want error", s, got)
+		}
+	}
+}
+
+func TestParseDateRange(t *testing.T) {
+	date := func(year int, month time.Month, day int) *time.Time {
+		d := time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+		return &d
+	}
+	// end returns the last moment before the given date.
+	end := func(year int, month time.Month, day int) *time.Time {
+		d := date(year, month, day).Add(-time.Nanosecond)
+		return &d
+	}
+	tests := []struct {
+		s            string
+		since, until *time.Time
+	}{
+		{"2019", date(2019, 1, 1), end(2020, 1, 1)},
+		{"2019-05", date(2019, 5, 1), end(2019, 6, 1)},
+		{"2019-12", date(2019, 12, 1), end(2020, 1, 1)},
+		{"2019-05-17", date(2019, 5, 17), end(2019, 5, 18)},
+		{" 2019-05-17 ", date(2019, 5, 17), end(2019, 5, 18)},
+		{"1990-1999", date(1990, 1, 1), end(2000, 1, 1)},
+		{"2019-05..2019-08", date(2019, 5, 1), end(2019, 9, 1)},
+		{"2019..2019-02-03", date(2019, 1, 1), end(2019, 2, 4)},
+		{"2024-03..", date(2024, 3, 1), nil},
+		{"..2019", nil, end(2020, 1, 1)},
+		{"since 2019-05", date(2019, 5, 1), nil},
+		{"Since 2019", date(2019, 1, 1), nil},
+	}
+	for _, test := range tests {
+		since, until, err := ParseDateRange(test.s)
+		if err != nil {
+			t.Errorf("ParseDateRange(%q): %s", test.s, err)
+			continue
+		}
+		if !equalTimes(since, test.since) || !equalTimes(until, test.until) {
+			t.Errorf("ParseDateRange(%q) = %v, %v, want %v, %v", test.s, since, until, test.since, test.until)
+		}
+	}
+	for _, s := range []string{"", "..", "2019-13", "2019-02-30", "19", "someday", "2019...2020",
+		"since", "7x", "1999-1990x"} {
+		if since, until, err := ParseDateRange(s); err == nil {
+			t.Errorf("ParseDateRange(%q) = %v, %v, want error", s, since, until)
+		}
+	}
+}
+
+func TestParseRelativeDates(t *testing.T) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	tests := []struct {
+		s     string
+		since time.Time
+	}{
+		{"today", today},
+		{"Today", today},
+		{"yesterday", today.AddDate(0, 0, -1)},
+	}
+	for _, test := range tests {
+		since, until, err := ParseDateRange(test.s)
+		if err != nil {
+			t.Errorf("ParseDateRange(%q): %s", test.s, err)
+			continue
+		}
+		if !since.Equal(test.since) || !until.Equal(test.since.AddDate(0, 0, 1).Add(-time.Nanosecond)) {
+			t.Errorf("ParseDateRange(%q) = %v, %v, want the day starting %v", test.s, since, until, test.since)
+		}
+	}
+
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		since, _, err := ParseDateRange(weekday.String())
+		if err != nil {
+			t.Errorf("ParseDateRange(%q): %s", weekday, err)
+			continue
+		}
+		if since.Weekday() != weekday || since.After(today) || !since.After(today.AddDate(0, 0, -7)) {
+			t.Errorf("ParseDateRange(%q) starts %v, want the last %s", weekday, since, weekday)
+		}
+	}
+
+	durations := []struct {
+		s     string
+		since time.Time
+	}{
+		{"12h", now.Add(-12 * time.Hour)},
+		{"7d", now.AddDate(0, 0, -7)},
+		{"2w", now.AddDate(0, 0, -14)},
+		{"3mo", now.AddDate(0, -3, 0)},
+		{"1y", now.AddDate(-1, 0, 0)},
+	}
+	for _, test := range durations {
+		since, until, err := ParseDateRange(test.s)
+		if err != nil {
+			t.Errorf("ParseDateRange(%q): %s", test.s, err)
+			continue
+		}
+		if since.Sub(test.since).Abs() > time.Minute || until.Sub(now).Abs() > time.Minute {
+			t.Errorf("ParseDateRange(%q) = %v, %v, want %v until now", test.s, since, until, test.since)
+		}
+	}
+}
+
+func equalTimes(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
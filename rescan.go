@@ -1,26 +1,43 @@
 package den
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
 	"maps"
+	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/codesoap/den/database"
 )
 
 // Rescan updates the database to contain up to date information
-// about files of all tracked paths.
-func Rescan(db database.DB, checkProgress, indexProgress chan Progress) error {
+// about files of the given directories, which must be tracked paths or
+// lie within one. If no directories are given, all tracked paths are
+// rescanned. New duplicate candidates are hashed afterwards. New and
+// changed files are analyzed by the given number of concurrent jobs.
+func Rescan(db database.DB, dirs []string, jobs int, checkProgress, indexProgress chan Progress) error {
 	defer close(indexProgress)
-	paths, err := db.TrackedPaths()
+	subtrees, err := findSubtrees(db, dirs)
 	if err != nil {
 		close(checkProgress)
-		return fmt.Errorf("could not query tracked paths: %s", err)
+		return err
 	}
 	prog := Progress{}
-	prog.Total, err = db.AllFileCount()
+	if len(dirs) == 0 {
+		prog.Total, err = db.AllFileCount()
+	} else {
+		for _, s := range subtrees {
+			var count int
+			if count, err = db.FileCountAt(s.dir); err != nil {
+				break
+			}
+			prog.Total += count
+		}
+	}
 	if err != nil {
 		close(checkProgress)
 		return fmt.Errorf("could not query total file count: %s", err)
@@ -31,12 +48,12 @@ func Rescan(db database.DB, checkProgress, indexProgress chan Progress) error {
 	}
 
 	toReindex := make(map[string]fs.DirEntry)
-	for i := range paths {
-		todo, err := rescanPath(db, paths[i], checkProgress, &prog)
+	for _, s := range subtrees {
+		todo, err := rescanPath(db, s, checkProgress, &prog)
 		if err != nil {
 			close(checkProgress)
 			_ = db.Rollback()
-			return fmt.Errorf("could not rescan path '%s': %s", paths[i], err)
+			return fmt.Errorf("could not rescan path '%s': %s", s.dir, err)
 		}
 		maps.Copy(toReindex, todo)
 	}
@@ -49,73 +66,107 @@ func Rescan(db database.DB, checkProgress, indexProgress chan Progress) error {
 		return fmt.Errorf("could not commit transaction: %s", err)
 	}
 
-	entriesInTx := 0
-	var lastProgressUpdate time.Time
 	prog = Progress{Total: len(toReindex)}
-	for path, entry := range toReindex {
-		if time.Since(lastProgressUpdate) >= time.Second {
-			indexProgress <- prog
-			lastProgressUpdate = time.Now()
+	if err = indexFiles(db, toReindex, jobs, indexProgress, &prog); err != nil {
+		return fmt.Errorf("could not index files: %s", err)
+	}
+	// Tags are only deleted now, so that the tags of reindexed files are
+	// kept:
+	if err = deleteOrphanedTags(db); err != nil {
+		return err
+	}
+	if err = hashDuplicateCandidates(db); err != nil {
+		return err
+	}
+	prog.Done = prog.Total
+	indexProgress <- prog
+	return nil
+}
+
+// subtree is a directory, that is a tracked path or lies within one.
+type subtree struct {
+	root string // The tracked path.
+	dir  string
+}
+
+// findSubtrees returns the subtrees of the given directories, leaving
+// out those within others. If no directories are given, the subtrees
+// of all tracked paths are returned.
+func findSubtrees(db database.DB, dirs []string) ([]subtree, error) {
+	roots, err := db.TrackedPaths()
+	if err != nil {
+		return nil, fmt.Errorf("could not query tracked paths: %s", err)
+	}
+	var subtrees []subtree
+	if len(dirs) == 0 {
+		for _, root := range roots {
+			subtrees = append(subtrees, subtree{root, root})
 		}
-		if entriesInTx == 0 {
-			if err := db.BeginTx(); err != nil {
-				return fmt.Errorf("could not start transaction: %s", err)
-			}
+		return subtrees, nil
+	}
+	dirs = slices.Clone(dirs)
+	slices.Sort(dirs)
+	for _, dir := range dirs {
+		if n := len(subtrees); n > 0 && within(dir, subtrees[n-1].dir) {
+			continue
 		}
-		entriesInTx++
-		if err = indexFile(db, path, entry); err != nil {
-			_ = db.Rollback()
-			return fmt.Errorf("could not index file '%s': %s", path, err)
+		// Directories, that have been removed, are rescanned to remove
+		// their files from the database.
+		if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+			return nil, fmt.Errorf("'%s' is not a directory", dir)
+		} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("could not get info on '%s': %s", dir, err)
 		}
-		if entriesInTx == 1_000 {
-			entriesInTx = 0
-			if err2 := db.Commit(); err2 != nil {
-				return fmt.Errorf("could not commit transaction: %s", err2)
+		s := subtree{dir: dir}
+		for _, root := range roots {
+			if within(dir, root) && len(root) > len(s.root) {
+				s.root = root
 			}
 		}
-		prog.Done++
-	}
-	if entriesInTx > 0 {
-		if err := db.Commit(); err != nil {
-			return fmt.Errorf("could not commit transaction: %s", err)
+		if s.root == "" {
+			return nil, fmt.Errorf("'%s' is not within a tracked path", dir)
 		}
+		subtrees = append(subtrees, s)
 	}
-	prog.Done = prog.Total
-	indexProgress <- prog
-	return nil
+	return subtrees, nil
 }
 
-// rescanPath compares all stored files below the given path with the
-// files found on the filesystem. Removed files are removed from the
-// database and files that should be newly indexed or re-indexed are
-// returned.
-func rescanPath(db database.DB, path string, progress chan Progress, prog *Progress) (map[string]fs.DirEntry, error) {
+// within reports whether path is dir or lies within it.
+func within(path, dir string) bool {
+	prefix := strings.TrimSuffix(dir, string(filepath.Separator)) + string(filepath.Separator)
+	return path == dir || strings.HasPrefix(path, prefix)
+}
+
+// rescanPath compares all stored files below the directory of s with
+// the files found on the filesystem. Removed and newly ignored files are
+// removed from the database and files that should be newly indexed or
+// re-indexed are returned.
+func rescanPath(db database.DB, s subtree, progress chan Progress, prog *Progress) (map[string]fs.DirEntry, error) {
 	// TODO: Use temporary SQLite table to use less memory:
-	oldInfos, err := db.AllFileInfosAt(path)
+	oldInfos, err := db.AllFileInfosAt(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	t, err := openTree(db, s.root)
 	if err != nil {
 		return nil, err
 	}
 	newInfos := make(map[string]fs.DirEntry)
+	excluded, err := t.excluded(s.dir)
+	if err != nil {
+		return nil, err
+	} else if excluded {
+		// Files that were indexed before are removed.
+		prog.Done += len(oldInfos)
+		return nil, db.DeletePaths(slices.Collect(maps.Keys(oldInfos)))
+	}
 	var lastProgressUpdate time.Time
-	err = filepath.WalkDir(path,
-		func(path string, d fs.DirEntry, err error) error {
+	err = t.walk(s.dir,
+		func(path string, d fs.DirEntry) error {
 			if time.Since(lastProgressUpdate) >= time.Second {
 				progress <- *prog
 				lastProgressUpdate = time.Now()
 			}
-			if err != nil {
-				return err
-			}
-			hidden, err := isHiddenFile(d.Name())
-			if err != nil {
-				f := "could not determine if '%s' is hidden: %s"
-				return fmt.Errorf(f, path, err)
-			} else if hidden {
-				if d.IsDir() {
-					return fs.SkipDir
-				}
-				return nil
-			}
 			if !d.Type().IsRegular() {
 				return nil
 			}
@@ -140,7 +191,7 @@ func rescanPath(db database.DB, path string, progress chan Progress, prog *Progr
 				f := "could not get info for file '%s': %s"
 				return nil, fmt.Errorf(f, path, err)
 			}
-			if oldInfo.Size != info.Size() ||
+			if oldInfo.Reindex || oldInfo.Size != info.Size() ||
 				oldInfo.ModTime != info.ModTime().Truncate(time.Second) {
 				// Deleting and adding anew reindexes the file:
 				deletePaths = append(deletePaths, path)
@@ -158,3 +209,14 @@ func rescanPath(db database.DB, path string, progress chan Progress, prog *Progr
 	}
 	return toIndex, nil
 }
+
+func deleteOrphanedTags(db database.DB) error {
+	if err := db.BeginTx(); err != nil {
+		return err
+	}
+	if err := db.DeleteOrphanedTags(); err != nil {
+		_ = db.Rollback()
+		return err
+	}
+	return db.Commit()
+}
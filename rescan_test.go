@@ -0,0 +1,111 @@
+package den
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/codesoap/den/database"
+	"github.com/codesoap/den/internal/querylang"
+)
+
+// newTestDB returns a new database in a temporary directory.
+func newTestDB(t *testing.T) database.DB {
+	t.Helper()
+	db, err := database.NewDB(filepath.Join(t.TempDir(), "db.sqlite3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func drain(progress chan Progress) {
+	go func() {
+		for range progress {
+		}
+	}()
+}
+
+func track(t *testing.T, db database.DB, path string) {
+	t.Helper()
+	progress := make(chan Progress)
+	drain(progress)
+	if err := Add(path, database.TrackOptions{}, db, 1, progress); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func rescan(t *testing.T, db database.DB) {
+	t.Helper()
+	checkProgress, indexProgress := make(chan Progress), make(chan Progress)
+	drain(checkProgress)
+	drain(indexProgress)
+	if err := Rescan(db, nil, 1, checkProgress, indexProgress); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// indexed returns the files of db, including members of archives, that
+// are at or below path, sorted by path.
+func indexed(t *testing.T, db database.DB, path string) []*database.File {
+	t.Helper()
+	e, err := querylang.Parse(`path:"` + path + `"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []*database.File
+	for entry, err := range db.Query(e, database.FileFilter{Sort: "path", InArchive: true}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, entry.Base())
+	}
+	return files
+}
+
+func writeFile(t *testing.T, path, content string, modified time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRescanKeepsTagsOfModifiedFiles(t *testing.T) {
+	db := newTestDB(t)
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	writeFile(t, a, "old", time.Unix(1_000_000, 0))
+	writeFile(t, b, "old", time.Unix(1_000_000, 0))
+	track(t, db, dir)
+	if err := db.TagFiles("keep", []string{a, b}); err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, a, "modified", time.Unix(2_000_000, 0))
+	if err := os.Remove(b); err != nil {
+		t.Fatal(err)
+	}
+	rescan(t, db)
+
+	files := indexed(t, db, dir)
+	if len(files) != 1 || files[0].Path != a || files[0].Size != int64(len("modified")) {
+		t.Fatalf("files after rescanning are %+v, want the modified %s", files, a)
+	}
+	tags, err := db.Tags(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []database.ValueCount{{Value: "keep", Count: 1}}; !slices.Equal(tags, want) {
+		t.Errorf("tags after rescanning are %v, want %v", tags, want)
+	}
+	if tags, err = db.Tags([]string{a}); err != nil {
+		t.Fatal(err)
+	} else if len(tags) != 1 {
+		t.Errorf("the modified file lost its tag")
+	}
+}
@@ -0,0 +1,222 @@
+package den
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/codesoap/den/database"
+	"github.com/codesoap/den/internal/ignore"
+)
+
+// tree is a tracked path together with the options, that decide which
+// files within it are indexed.
+type tree struct {
+	root    string
+	opts    database.TrackOptions
+	ignores *ignore.Matcher
+
+	// device is the device holding root. It is only known if
+	// directories on other devices are skipped.
+	device      uint64
+	knownDevice bool
+}
+
+// openTree returns the tree of the tracked path root with the options
+// stored in db.
+func openTree(db database.DB, root string) (*tree, error) {
+	opts, err := db.TrackOptions(root)
+	if err != nil {
+		return nil, err
+	}
+	return newTree(root, opts)
+}
+
+func newTree(root string, opts database.TrackOptions) (*tree, error) {
+	ignores, err := ignore.New(root, opts.IgnorePatterns)
+	if err != nil {
+		return nil, err
+	}
+	t := &tree{root: root, opts: opts, ignores: ignores}
+	if opts.SameFilesystem {
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, fmt.Errorf("could not get info on '%s': %s", root, err)
+		}
+		t.device, t.knownDevice = device(info)
+	}
+	return t, nil
+}
+
+// skip reports whether the file at path is ignored or hidden, while
+// hidden files are not included. Directories for which true is
+// returned should be skipped. The root itself is never skipped.
+func (t *tree) skip(path string, isDir bool) (bool, error) {
+	if path == t.root {
+		return false, nil
+	}
+	if !t.opts.Hidden {
+		hidden, err := isHiddenFile(filepath.Base(path))
+		if err != nil {
+			return false, fmt.Errorf("could not determine if '%s' is hidden: %s", path, err)
+		} else if hidden {
+			return true, nil
+		}
+	}
+	ignored, err := t.ignores.Ignored(path, isDir)
+	if err != nil {
+		return false, fmt.Errorf("could not read ignore patterns for '%s': %s", path, err)
+	}
+	return ignored, nil
+}
+
+// excluded reports whether path, which must lie below the root, or any
+// directory containing it is skipped or on another device, while those
+// are skipped. path must be a directory.
+func (t *tree) excluded(path string) (bool, error) {
+	rel, err := filepath.Rel(t.root, path)
+	if err != nil || rel == "." {
+		return false, err
+	}
+	dir := t.root
+	for _, name := range strings.Split(rel, string(filepath.Separator)) {
+		dir = filepath.Join(dir, name)
+		if skip, err := t.skip(dir, true); err != nil || skip {
+			return skip, err
+		}
+	}
+	if t.knownDevice {
+		if info, err := os.Stat(path); err == nil && t.otherDevice(info) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// otherDevice reports whether info describes a file on another device
+// than the root, while those are skipped.
+func (t *tree) otherDevice(info fs.FileInfo) bool {
+	if !t.knownDevice {
+		return false
+	}
+	d, ok := device(info)
+	return ok && d != t.device
+}
+
+// walk calls fn for start and all files and directories below it, that
+// are not skipped. start must be within the root of t. If symbolic
+// links are followed, fn gets the path of the link together with an
+// entry describing its target. Linked directories, that are being
+// walked already, are not walked again, so that cycles are avoided.
+func (t *tree) walk(start string, fn func(path string, d fs.DirEntry) error) error {
+	real, visited := start, (*[]string)(nil)
+	var err error
+	if t.opts.FollowSymlinks {
+		root, err := filepath.EvalSymlinks(t.root)
+		if err != nil {
+			return err
+		}
+		visited = &[]string{root}
+		real, err = filepath.EvalSymlinks(start)
+	}
+	var info fs.FileInfo
+	if err == nil {
+		info, err = os.Lstat(real)
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		// Removed in the meantime.
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err = fn(start, fs.FileInfoToDirEntry(info)); err == fs.SkipDir {
+		return nil
+	} else if err != nil || !info.IsDir() {
+		return err
+	}
+	return t.walkDir(start, real, visited, fn)
+}
+
+// walkDir walks the directory real, but passes paths to fn as if it was
+// located at dir. fn is not called for dir itself. visited holds the
+// real paths of the directories that are walked already; it is nil if
+// symbolic links are not followed.
+func (t *tree) walkDir(dir, real string, visited *[]string, fn func(path string, d fs.DirEntry) error) error {
+	return filepath.WalkDir(real,
+		func(p string, d fs.DirEntry, err error) error {
+			if errors.Is(err, fs.ErrNotExist) {
+				// Removed in the meantime.
+				return nil
+			} else if err != nil {
+				return err
+			} else if p == real {
+				return nil
+			}
+			path := dir + strings.TrimPrefix(p, real)
+			if visited != nil && d.Type()&fs.ModeSymlink != 0 {
+				return t.follow(path, visited, fn)
+			}
+			if skip, err := t.skip(path, d.IsDir()); err != nil {
+				return err
+			} else if skip {
+				if d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() {
+				if visited != nil && slices.Contains(*visited, p) {
+					// Walked on its own, because a link points to it.
+					return fs.SkipDir
+				}
+				if t.knownDevice {
+					info, err := d.Info()
+					if err != nil {
+						return err
+					} else if t.otherDevice(info) {
+						return fs.SkipDir
+					}
+				}
+			}
+			return fn(path, d)
+		})
+}
+
+// follow calls fn for the symbolic link at path and walks its target,
+// if it is a directory. Dangling links are skipped.
+func (t *tree) follow(path string, visited *[]string, fn func(path string, d fs.DirEntry) error) error {
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+	if skip, err := t.skip(path, info.IsDir()); err != nil || skip {
+		return err
+	}
+	d := fs.FileInfoToDirEntry(info)
+	if !info.IsDir() {
+		return fn(path, d)
+	} else if t.otherDevice(info) {
+		return nil
+	}
+	for _, v := range *visited {
+		if real == v || strings.HasPrefix(real, v+string(filepath.Separator)) {
+			// The target is walked already, or the link forms a cycle.
+			return nil
+		}
+	}
+	*visited = append(*visited, real)
+	if err = fn(path, d); err == fs.SkipDir {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return t.walkDir(path, real, visited, fn)
+}
@@ -0,0 +1,77 @@
+package den
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/codesoap/den/database"
+)
+
+// walked returns the paths, relative to root, that t.walk passes to fn
+// when walking root.
+func walked(t *testing.T, root string, opts database.TrackOptions) []string {
+	t.Helper()
+	tree, err := newTree(root, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	err = tree.walk(root, func(path string, d fs.DirEntry) error {
+		if len(paths) > 100 {
+			return fmt.Errorf("walked too many paths, e.g. %s", path)
+		}
+		rel, err := filepath.Rel(root, path)
+		paths = append(paths, filepath.ToSlash(rel))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+func symlink(t *testing.T, target, link string) {
+	t.Helper()
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("could not create symbolic link: %s", err)
+	}
+}
+
+func TestWalkFollowsSymlinksWithoutLooping(t *testing.T) {
+	dir := t.TempDir()
+	root, outside := filepath.Join(dir, "root"), filepath.Join(dir, "outside")
+	for _, d := range []string{filepath.Join(root, "a"), outside} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range []string{filepath.Join(root, "a", "f.txt"), filepath.Join(outside, "g.txt")} {
+		if err := os.WriteFile(f, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	symlink(t, "..", filepath.Join(root, "a", "up"))
+	symlink(t, ".", filepath.Join(root, "a", "self"))
+	symlink(t, "a", filepath.Join(root, "b"))
+	symlink(t, filepath.Join("a", "f.txt"), filepath.Join(root, "l.txt"))
+	symlink(t, "missing", filepath.Join(root, "dangling"))
+	symlink(t, outside, filepath.Join(root, "ext"))
+	symlink(t, root, filepath.Join(outside, "back"))
+	symlink(t, ".", filepath.Join(outside, "again"))
+
+	// Links to directories that are walked already are left out, so
+	// that every file is found only once:
+	want := []string{".", "a", "a/f.txt", "ext", "ext/g.txt", "l.txt"}
+	if got := walked(t, root, database.TrackOptions{FollowSymlinks: true}); !slices.Equal(got, want) {
+		t.Errorf("walked %q, want %q", got, want)
+	}
+	want = []string{".", "a", "a/f.txt", "a/self", "a/up", "b", "dangling", "ext", "l.txt"}
+	if got := walked(t, root, database.TrackOptions{}); !slices.Equal(got, want) {
+		t.Errorf("walked %q without following links, want %q", got, want)
+	}
+}
@@ -0,0 +1,112 @@
+package den
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"runtime"
+	"slices"
+
+	"github.com/codesoap/den/database"
+)
+
+// WatchUpdate summarizes the changes that have been written to the
+// database in one batch by Watch.
+type WatchUpdate struct {
+	Indexed, Removed int
+
+	// Rescanned is true, if changes were lost and a full rescan was
+	// done instead.
+	Rescanned bool
+}
+
+type watchOp int
+
+const (
+	// opIndex (re-)indexes a file. If the file does not exist anymore,
+	// it is removed instead.
+	opIndex watchOp = iota
+
+	// opRemove removes a file or all files within a directory.
+	opRemove
+)
+
+// maxPendingChanges is the number of changes after which Watch writes
+// to the database without waiting any longer.
+const maxPendingChanges = 1_000
+
+// applyChanges writes the given changes to the database in a single
+// transaction. Removals are applied first, so that files that have
+// been created anew within a removed directory are kept.
+func applyChanges(db database.DB, changes map[string]watchOp) (WatchUpdate, error) {
+	update := WatchUpdate{}
+	if err := db.BeginTx(); err != nil {
+		return update, fmt.Errorf("could not start transaction: %s", err)
+	}
+	paths := slices.Sorted(maps.Keys(changes))
+	for _, path := range paths {
+		if changes[path] != opRemove {
+			continue
+		}
+		n, err := db.DeleteTree(path)
+		if err != nil {
+			_ = db.Rollback()
+			return update, fmt.Errorf("could not delete info on '%s': %s", path, err)
+		}
+		update.Removed += n
+	}
+	for _, path := range paths {
+		if changes[path] != opIndex {
+			continue
+		}
+		info, err := os.Lstat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			n, err := db.DeleteTree(path)
+			if err != nil {
+				_ = db.Rollback()
+				return update, fmt.Errorf("could not delete info on '%s': %s", path, err)
+			}
+			update.Removed += n
+			continue
+		} else if err != nil {
+			_ = db.Rollback()
+			return update, fmt.Errorf("could not get info on '%s': %s", path, err)
+		} else if !info.Mode().IsRegular() {
+			continue
+		}
+		// Deleting and adding anew reindexes the file:
+		if err = db.DeletePaths([]string{path}); err != nil {
+			_ = db.Rollback()
+			return update, fmt.Errorf("could not delete info on '%s': %s", path, err)
+		}
+		if err = indexFile(db, path, fs.FileInfoToDirEntry(info)); err != nil {
+			_ = db.Rollback()
+			return update, err
+		}
+		update.Indexed++
+	}
+	if err := db.DeleteOrphanedTags(); err != nil {
+		_ = db.Rollback()
+		return update, err
+	}
+	if err := db.Commit(); err != nil {
+		return update, fmt.Errorf("could not commit transaction: %s", err)
+	}
+	return update, hashDuplicateCandidates(db)
+}
+
+// rescanAfterOverflow does a full rescan, because events have been
+// lost.
+func rescanAfterOverflow(db database.DB) error {
+	checkProgress := make(chan Progress)
+	indexProgress := make(chan Progress)
+	go func() {
+		for range checkProgress {
+		}
+		for range indexProgress {
+		}
+	}()
+	return Rescan(db, nil, runtime.NumCPU(), checkProgress, indexProgress)
+}
@@ -0,0 +1,279 @@
+//go:build linux
+
+package den
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/codesoap/den/database"
+	"github.com/codesoap/den/internal/ignore"
+)
+
+const (
+	fileEvents = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE |
+		syscall.IN_ATTRIB | syscall.IN_MOVED_TO
+	removeEvents = syscall.IN_DELETE | syscall.IN_MOVED_FROM
+	watchMask    = fileEvents | removeEvents | syscall.IN_ONLYDIR
+)
+
+type inotifyEvent struct {
+	wd   int32
+	mask uint32
+	name string
+}
+
+type watcher struct {
+	fd    int
+	paths map[int32]string // Watched directory by watch descriptor.
+
+	// trees holds the options of walking each tracked path.
+	trees map[string]*tree
+
+	// pending holds the changes that have not yet been written to the
+	// database.
+	pending map[string]watchOp
+}
+
+// Watch watches all tracked paths for changes and updates the database
+// accordingly. Changes are collected for about a second and then
+// written in one transaction. A summary of each written batch is sent
+// to updates.
+//
+// Watch runs until an error occurs. Paths that are tracked after Watch
+// has been started are not watched.
+func Watch(db database.DB, updates chan WatchUpdate) error {
+	defer close(updates)
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return fmt.Errorf("could not initialize inotify: %s", err)
+	}
+	// Reading through an os.File uses the runtime's poller, so that
+	// closing the file ends a pending read:
+	file := os.NewFile(uintptr(fd), "inotify")
+	defer file.Close()
+	w := watcher{
+		fd:      fd,
+		paths:   make(map[int32]string),
+		trees:   make(map[string]*tree),
+		pending: make(map[string]watchOp),
+	}
+	events := make(chan inotifyEvent)
+	readErr := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		readErr <- w.read(file, events, done)
+	}()
+
+	paths, err := db.TrackedPaths()
+	if err != nil {
+		return fmt.Errorf("could not query tracked paths: %s", err)
+	}
+	for _, path := range paths {
+		if w.trees[path], err = openTree(db, path); err != nil {
+			return err
+		}
+		// Files are not queued for indexing here; use Rescan to catch up
+		// on changes from before Watch was started.
+		if err = w.addTree(path, false); err != nil {
+			return err
+		}
+	}
+
+	flush := time.NewTimer(time.Second)
+	flush.Stop()
+	flushPending := false
+	for {
+		select {
+		case err := <-readErr:
+			return fmt.Errorf("could not read inotify events: %s", err)
+		case event := <-events:
+			overflowed, err := w.handle(event)
+			if err != nil {
+				return err
+			} else if overflowed {
+				// Directories may have been created or moved without
+				// notice, so the watches are renewed before rescanning:
+				if err = w.renewWatches(); err != nil {
+					return err
+				}
+				if err = rescanAfterOverflow(db); err != nil {
+					return fmt.Errorf("could not rescan after lost events: %s", err)
+				}
+				clear(w.pending)
+				updates <- WatchUpdate{Rescanned: true}
+				continue
+			}
+			if len(w.pending) >= maxPendingChanges {
+				flush.Reset(0)
+				flushPending = true
+			} else if len(w.pending) > 0 && !flushPending {
+				flush.Reset(time.Second)
+				flushPending = true
+			}
+		case <-flush.C:
+			flushPending = false
+			if len(w.pending) == 0 {
+				continue
+			}
+			update, err := applyChanges(db, w.pending)
+			if err != nil {
+				return err
+			}
+			clear(w.pending)
+			updates <- update
+		}
+	}
+}
+
+// read reads events from the inotify file and sends them to events,
+// until reading fails or done is closed.
+func (w *watcher) read(file *os.File, events chan inotifyEvent, done chan struct{}) error {
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := file.Read(buf)
+		if err != nil {
+			return err
+		}
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			raw := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + syscall.SizeofInotifyEvent
+			name := string(buf[nameStart : nameStart+int(raw.Len)])
+			event := inotifyEvent{
+				wd:   raw.Wd,
+				mask: raw.Mask,
+				name: strings.TrimRight(name, "\x00"),
+			}
+			select {
+			case events <- event:
+			case <-done:
+				return nil
+			}
+			offset = nameStart + int(raw.Len)
+		}
+	}
+}
+
+// handle records the change described by event in w.pending. If events
+// have been lost, true is returned.
+func (w *watcher) handle(event inotifyEvent) (bool, error) {
+	if event.mask&syscall.IN_Q_OVERFLOW != 0 {
+		return true, nil
+	} else if event.mask&syscall.IN_IGNORED != 0 {
+		delete(w.paths, event.wd)
+		return false, nil
+	}
+	dir, ok := w.paths[event.wd]
+	if !ok || event.name == "" {
+		return false, nil
+	}
+	path := filepath.Join(dir, event.name)
+	isDir := event.mask&syscall.IN_ISDIR != 0
+	t := w.tree(path)
+	if event.name == ignore.FileName {
+		// Changed patterns apply to new events; rescan to apply them to
+		// files that have already been indexed.
+		t.ignores.Forget(dir)
+		return false, nil
+	}
+	if skip, err := t.skip(path, isDir); err != nil {
+		return false, err
+	} else if skip {
+		return false, nil
+	}
+	switch {
+	case event.mask&removeEvents != 0:
+		if isDir {
+			w.removeWatches(path)
+		}
+		w.pending[path] = opRemove
+	case isDir && event.mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0:
+		// Files may have been created before the watch was added, so all
+		// files within the new directory are indexed:
+		if err := w.addTree(path, true); err != nil {
+			return false, err
+		}
+	case !isDir && event.mask&fileEvents != 0:
+		w.pending[path] = opIndex
+	}
+	return false, nil
+}
+
+// addTree adds watches for root and all directories below it, which
+// are not skipped according to the options of the tracked path. If
+// index is true, all files found are queued for indexing.
+func (w *watcher) addTree(root string, index bool) error {
+	err := w.tree(root).walk(root,
+		func(path string, d fs.DirEntry) error {
+			if d.IsDir() {
+				wd, err := syscall.InotifyAddWatch(w.fd, path, watchMask)
+				if errors.Is(err, syscall.ENOSPC) {
+					return fmt.Errorf("too many watches; consider increasing fs.inotify.max_user_watches")
+				} else if errors.Is(err, syscall.ENOENT) {
+					return fs.SkipDir
+				} else if err != nil {
+					return fmt.Errorf("could not watch '%s': %s", path, err)
+				}
+				w.paths[int32(wd)] = path
+			} else if index && d.Type().IsRegular() {
+				w.pending[path] = opIndex
+			}
+			return nil
+		})
+	if err != nil {
+		return fmt.Errorf("could not watch '%s': %s", root, err)
+	}
+	return nil
+}
+
+// renewWatches walks all tracked paths again to watch directories,
+// whose creation was missed, and stops watching directories that are no
+// longer found within them.
+func (w *watcher) renewWatches() error {
+	old := w.paths
+	w.paths = make(map[int32]string)
+	for root := range w.trees {
+		if err := w.addTree(root, false); err != nil {
+			return err
+		}
+	}
+	for wd := range old {
+		if _, ok := w.paths[wd]; !ok {
+			_, _ = syscall.InotifyRmWatch(w.fd, uint32(wd))
+		}
+	}
+	return nil
+}
+
+// removeWatches removes the watches for dir and all directories below
+// it. This is necessary when a directory is moved, because the watches
+// would otherwise report events with outdated paths.
+func (w *watcher) removeWatches(dir string) {
+	prefix := dir + string(filepath.Separator)
+	for wd, path := range w.paths {
+		if path == dir || strings.HasPrefix(path, prefix) {
+			_, _ = syscall.InotifyRmWatch(w.fd, uint32(wd))
+			delete(w.paths, wd)
+		}
+	}
+}
+
+// tree returns the tree of the tracked path containing path.
+func (w *watcher) tree(path string) *tree {
+	for root, t := range w.trees {
+		if path == root || strings.HasPrefix(path, root+string(filepath.Separator)) {
+			return t
+		}
+	}
+	// Not reached, because only tracked paths are watched.
+	t, _ := newTree(path, database.TrackOptions{})
+	return t
+}
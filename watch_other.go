@@ -0,0 +1,15 @@
+//go:build !linux
+
+package den
+
+import (
+	"fmt"
+
+	"github.com/codesoap/den/database"
+)
+
+// Watch is only supported on Linux.
+func Watch(db database.DB, updates chan WatchUpdate) error {
+	close(updates)
+	return fmt.Errorf("watching is only supported on Linux")
+}